@@ -3,7 +3,6 @@ module github.com/DerekCorniello/pip-req-valid
 go 1.23.2
 
 require (
-	github.com/Masterminds/semver/v3 v3.3.1
 	github.com/golang-jwt/jwt/v4 v4.5.1
 	github.com/joho/godotenv v1.5.1
 	golang.org/x/time v0.8.0
@@ -0,0 +1,291 @@
+package index
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+)
+
+// ErrNotCached is what offline mode gives back for anything it hasn't
+// seen before.
+var ErrNotCached = errors.New("not in the cache and offline mode is on")
+
+const DefaultCacheTTL = 10 * time.Minute
+
+// evictInterval is how often a store walks the cache even though the
+// size it keeps count of still fits, for MaxAge and for other processes
+// sharing the directory
+const evictInterval = time.Minute
+
+// Cache keeps index responses on disk, one directory per normalized
+// package name. Entries younger than TTL are served as is, older ones
+// are revalidated with If-None-Match / If-Modified-Since so an
+// unchanged document costs a 304 instead of the whole download.
+type Cache struct {
+	Dir string
+	// how long an entry is trusted without asking the index again,
+	// zero means DefaultCacheTTL
+	TTL time.Duration
+	// entries not used in this long get deleted, zero keeps them
+	MaxAge time.Duration
+	// once the cache grows past this the least recently used entries
+	// go, zero means no limit
+	MaxBytes int64
+	// only ever answer from the cache, never touch the network
+	Offline bool
+
+	// evictions walk the whole directory, only let one run at a time
+	evicting sync.Mutex
+	// the size as of the last walk plus what's been stored since, so a
+	// store only walks the directory when it's due
+	mu        sync.Mutex
+	size      int64
+	lastEvict time.Time
+}
+
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	Status       int       `json:"status"`
+	ContentType  string    `json:"content_type"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	StoredAt     time.Time `json:"stored_at"`
+	Body         []byte    `json:"body"`
+}
+
+// response is the part of an HTTP response the index clients look at
+type response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+func (c *Cache) ttl() time.Duration {
+	if c.TTL <= 0 {
+		return DefaultCacheTTL
+	}
+	return c.TTL
+}
+
+func (c *Cache) path(name string, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, pep508.NormalizeName(name), hex.EncodeToString(sum[:16])+".json")
+}
+
+func (c *Cache) load(path string) (*cacheEntry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return nil, err
+	}
+	// the file time tracks last use for the eviction
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return &entry, nil
+}
+
+func (c *Cache) store(path string, entry *cacheEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var replaced int64
+	if info, err := os.Stat(path); err == nil {
+		replaced = info.Size()
+	}
+	// write then rename so readers never see half a file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mu.Lock()
+	c.size += int64(len(content)) - replaced
+	c.mu.Unlock()
+	if !c.evictionDue() {
+		return nil
+	}
+	return c.evict()
+}
+
+// evictionDue is true on the first store, once the stored bytes go past
+// MaxBytes and every evictInterval after that.
+func (c *Cache) evictionDue() bool {
+	if c.MaxAge <= 0 && c.MaxBytes <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastEvict.IsZero() || time.Since(c.lastEvict) >= evictInterval || (c.MaxBytes > 0 && c.size > c.MaxBytes)
+}
+
+func (e *cacheEntry) response() *response {
+	return &response{Status: e.Status, ContentType: e.ContentType, Body: e.Body}
+}
+
+// get fetches url for the package name through the cache. prepare sets
+// up headers (auth, Accept) on the request before it goes out.
+func (c *Cache) get(ctx context.Context, client *http.Client, name string, url string, prepare func(*http.Request)) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	prepare(req)
+
+	if c == nil {
+		return doRequest(client, req)
+	}
+
+	// different Accept headers get different documents
+	path := c.path(name, url+"\n"+req.Header.Get("Accept"))
+	entry, loadErr := c.load(path)
+	if loadErr == nil && (c.Offline || time.Since(entry.StoredAt) < c.ttl()) {
+		return entry.response(), nil
+	}
+	if c.Offline {
+		return nil, fmt.Errorf("%s: %w", url, ErrNotCached)
+	}
+
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		entry.StoredAt = time.Now()
+		c.store(path, entry)
+		return entry.response(), nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	fresh := &cacheEntry{
+		URL:          url,
+		Status:       resp.StatusCode,
+		ContentType:  resp.Header.Get("Content-Type"),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		StoredAt:     time.Now(),
+		Body:         body,
+	}
+	// only keep answers that mean something, not server hiccups
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotFound {
+		c.store(path, fresh)
+	}
+	return fresh.response(), nil
+}
+
+func doRequest(client *http.Client, req *http.Request) (*response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &response{Status: resp.StatusCode, ContentType: resp.Header.Get("Content-Type"), Body: body}, nil
+}
+
+// cached is a file in the cache as evict sees it
+type cached struct {
+	path    string
+	size    int64
+	touched time.Time
+}
+
+// evict drops entries older than MaxAge, then the least recently used
+// ones until the cache fits in MaxBytes.
+func (c *Cache) evict() error {
+	c.evicting.Lock()
+	defer c.evicting.Unlock()
+	// another store may have just done it
+	if !c.evictionDue() {
+		return nil
+	}
+
+	entries := []cached{}
+	var total int64
+	err := filepath.WalkDir(c.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if c.MaxAge > 0 && time.Since(info.ModTime()) > c.MaxAge {
+			os.Remove(path)
+			return nil
+		}
+		entries = append(entries, cached{path: path, size: info.Size(), touched: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err == nil && c.MaxBytes > 0 && total > c.MaxBytes {
+		total = c.removeOldest(entries, total)
+	}
+
+	c.mu.Lock()
+	c.size, c.lastEvict = total, time.Now()
+	c.mu.Unlock()
+	return err
+}
+
+// removeOldest deletes the least recently used entries until the cache
+// fits in MaxBytes, and gives back the size left.
+func (c *Cache) removeOldest(entries []cached, total int64) int64 {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].touched.Before(entries[j].touched)
+	})
+	for _, entry := range entries {
+		if total <= c.MaxBytes {
+			break
+		}
+		if os.Remove(entry.path) == nil {
+			total -= entry.size
+		}
+	}
+	return total
+}
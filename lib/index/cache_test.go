@@ -0,0 +1,94 @@
+package index
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheEviction(t *testing.T) {
+	entry := func(size int) *cacheEntry {
+		return &cacheEntry{URL: "https://pypi.org/simple/demo/", Status: 200, Body: []byte(strings.Repeat("x", size))}
+	}
+	onDisk := func(e *cacheEntry) int64 {
+		content, _ := json.Marshal(e)
+		return int64(len(content))
+	}
+	// room for a small and the big entry, not all three
+	cache := &Cache{Dir: t.TempDir(), MaxBytes: onDisk(entry(100)) + onDisk(entry(300))}
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+
+	first := cache.path("demo", "first")
+	if err := cache.store(first, entry(100)); err != nil {
+		t.Fatal(err)
+	}
+	walked := cache.lastEvict
+	if walked.IsZero() {
+		t.Fatalf("the first store didn't walk the cache")
+	}
+	// make the first entry the least recently used
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(first, old, old)
+
+	second := cache.path("demo", "second")
+	if err := cache.store(second, entry(100)); err != nil {
+		t.Fatal(err)
+	}
+	if cache.lastEvict != walked {
+		t.Errorf("a store that fits walked the cache again")
+	}
+	// replacing an entry only counts the difference
+	before := cache.size
+	if err := cache.store(second, entry(100)); err != nil {
+		t.Fatal(err)
+	}
+	if cache.size != before {
+		t.Errorf("size after replacing an entry = %d, want %d", cache.size, before)
+	}
+
+	third := cache.path("other", "third")
+	if err := cache.store(third, entry(300)); err != nil {
+		t.Fatal(err)
+	}
+	if exists(first) || !exists(second) || !exists(third) {
+		t.Errorf("going over MaxBytes should only drop the least recently used entry")
+	}
+	if cache.size > cache.MaxBytes {
+		t.Errorf("size after eviction = %d, want at most %d", cache.size, cache.MaxBytes)
+	}
+}
+
+func TestCacheMaxAge(t *testing.T) {
+	cache := &Cache{Dir: t.TempDir(), MaxAge: time.Hour}
+	stale := cache.path("demo", "stale")
+	if err := cache.store(stale, &cacheEntry{Status: 200}); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(stale, old, old)
+
+	// within the interval the stale entry is left for the next walk
+	fresh := cache.path("demo", "fresh")
+	cache.store(fresh, &cacheEntry{Status: 200})
+	if _, err := os.Stat(stale); err != nil {
+		t.Fatalf("a store inside evictInterval walked the cache")
+	}
+
+	cache.lastEvict = time.Now().Add(-evictInterval)
+	cache.store(fresh, &cacheEntry{Status: 200})
+	if _, err := os.Stat(stale); err == nil {
+		t.Errorf("the entry older than MaxAge is still there")
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("the fresh entry was removed: %v", err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(cache.Dir, "demo", ".tmp-*")); len(matches) > 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
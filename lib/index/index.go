@@ -0,0 +1,90 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound means the index answered, it just doesn't have the
+// package (or the version asked for).
+var ErrNotFound = errors.New("package not found")
+
+// ErrNoMetadata means the index has the release but its metadata
+// couldn't be read, so its dependencies aren't known.
+var ErrNoMetadata = errors.New("no readable metadata")
+
+// ErrNoIndex is what an empty Multi gives back, like pip with --no-index.
+var ErrNoIndex = errors.New("no index is configured (--no-index)")
+
+// PackageIndex is anything we can look packages up on, PyPI, a private
+// mirror or a fake for tests.
+type PackageIndex interface {
+	// Versions lists every version the index has for the package,
+	// including yanked ones.
+	Versions(ctx context.Context, name string) ([]Version, error)
+	// Release gets the core metadata of one version of the package.
+	Release(ctx context.Context, name string, version string) (*Release, error)
+	// Files lists the distribution files uploaded for one version.
+	Files(ctx context.Context, name string, version string) ([]File, error)
+}
+
+// Version is one version of a package as the index lists it.
+type Version struct {
+	Version string `json:"version"`
+	// PEP 592, pip only installs a yanked version when it's pinned
+	// exactly
+	Yanked       bool   `json:"yanked,omitempty"`
+	YankedReason string `json:"yanked_reason,omitempty"`
+	// the interpreters the version supports, like ">=3.8"
+	RequiresPython string `json:"requires_python,omitempty"`
+}
+
+// versionOf works out whether a version is yanked from its files, it is
+// once every one of them is. The files of a version nearly always agree
+// on Requires-Python, the first one that has it wins.
+func versionOf(version string, files []File) Version {
+	v := Version{Version: version, Yanked: len(files) > 0}
+	for _, file := range files {
+		v.Yanked = v.Yanked && file.Yanked
+		if file.YankedReason != "" && v.YankedReason == "" {
+			v.YankedReason = file.YankedReason
+		}
+		if file.RequiresPython != "" && v.RequiresPython == "" {
+			v.RequiresPython = file.RequiresPython
+		}
+	}
+	if !v.Yanked {
+		v.YankedReason = ""
+	}
+	return v
+}
+
+// Release is the metadata for a single version of a package.
+type Release struct {
+	Name              string   `json:"name"`
+	Version           string   `json:"version"`
+	Summary           string   `json:"summary,omitempty"`
+	Description       string   `json:"description,omitempty"`
+	RequiresDist      []string `json:"requires_dist,omitempty"`
+	RequiresPython    string   `json:"requires_python,omitempty"`
+	License           string   `json:"license,omitempty"`
+	LicenseExpression string   `json:"license_expression,omitempty"`
+	Classifiers       []string `json:"classifiers,omitempty"`
+	Yanked            bool     `json:"yanked,omitempty"`
+	YankedReason      string   `json:"yanked_reason,omitempty"`
+}
+
+// File is one uploaded distribution, a wheel or an sdist.
+type File struct {
+	Filename string `json:"filename"`
+	URL      string `json:"url,omitempty"`
+	// algorithm name to hex digest, like "sha256": "ab12..."
+	Digests        map[string]string `json:"digests,omitempty"`
+	RequiresPython string            `json:"requires_python,omitempty"`
+	Yanked         bool              `json:"yanked,omitempty"`
+	YankedReason   string            `json:"yanked_reason,omitempty"`
+	UploadTime     time.Time         `json:"upload_time,omitempty"`
+	// "bdist_wheel" or "sdist"
+	PackageType string `json:"packagetype,omitempty"`
+}
@@ -0,0 +1,117 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+)
+
+// Memory is an index held entirely in memory, keyed by the normalized
+// package name. It's the fake the tests run against so nothing goes out
+// to PyPI.
+type Memory map[string]*Project
+
+// Project is every release of a package along with its files.
+type Project struct {
+	Name     string                     `json:"name"`
+	Releases map[string]*ProjectRelease `json:"releases"`
+}
+
+type ProjectRelease struct {
+	Release
+	Files []File `json:"files,omitempty"`
+}
+
+// LoadDir builds a Memory index out of a directory of `<name>.json`
+// files, each one a Project.
+func LoadDir(root string) (Memory, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	mem := Memory{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(root, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var project Project
+		if err := json.Unmarshal(content, &project); err != nil {
+			return nil, fmt.Errorf("invalid project file %s: %v", entry.Name(), err)
+		}
+		if project.Name == "" {
+			project.Name = strings.TrimSuffix(entry.Name(), ".json")
+		}
+		mem[pep508.NormalizeName(project.Name)] = &project
+	}
+	return mem, nil
+}
+
+func (mem Memory) project(name string) (*Project, error) {
+	project, ok := mem[pep508.NormalizeName(name)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return project, nil
+}
+
+func (mem Memory) release(name string, version string) (*ProjectRelease, error) {
+	project, err := mem.project(name)
+	if err != nil {
+		return nil, err
+	}
+	release, ok := project.Releases[version]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return release, nil
+}
+
+func (mem Memory) Versions(ctx context.Context, name string) ([]Version, error) {
+	project, err := mem.project(name)
+	if err != nil {
+		return nil, err
+	}
+	versions := []Version{}
+	for version, release := range project.Releases {
+		v := versionOf(version, release.Files)
+		if release.Yanked {
+			v.Yanked, v.YankedReason = true, release.YankedReason
+		}
+		if release.RequiresPython != "" {
+			v.RequiresPython = release.RequiresPython
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func (mem Memory) Release(ctx context.Context, name string, version string) (*Release, error) {
+	release, err := mem.release(name, version)
+	if err != nil {
+		return nil, err
+	}
+	meta := release.Release
+	if meta.Name == "" {
+		project, _ := mem.project(name)
+		meta.Name = project.Name
+	}
+	meta.Version = version
+	return &meta, nil
+}
+
+func (mem Memory) Files(ctx context.Context, name string, version string) ([]File, error) {
+	release, err := mem.release(name, version)
+	if err != nil {
+		return nil, err
+	}
+	return release.Files, nil
+}
@@ -0,0 +1,37 @@
+package index
+
+import (
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+)
+
+// parseCoreMetadata reads a METADATA / PKG-INFO file, the email header
+// style format wheels and sdists carry their metadata in.
+func parseCoreMetadata(r io.Reader) (*Release, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid core metadata: %v", err)
+	}
+	header := msg.Header
+	release := &Release{
+		Name:              header.Get("Name"),
+		Version:           header.Get("Version"),
+		Summary:           header.Get("Summary"),
+		Description:       header.Get("Description"),
+		RequiresDist:      header["Requires-Dist"],
+		RequiresPython:    header.Get("Requires-Python"),
+		License:           header.Get("License"),
+		LicenseExpression: header.Get("License-Expression"),
+		Classifiers:       header["Classifier"],
+	}
+	// newer metadata puts the description in the body instead
+	if release.Description == "" {
+		body, err := io.ReadAll(msg.Body)
+		if err == nil {
+			release.Description = strings.TrimSpace(string(body))
+		}
+	}
+	return release, nil
+}
@@ -0,0 +1,195 @@
+package index
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Multi looks packages up on several indexes the way pip does with
+// --extra-index-url. The versions of every index get pooled, asking all
+// of them at once, and the metadata and files for a version come from
+// the first index in the list that has it, so the order is the
+// priority: --index-url first, then each --extra-index-url in the order
+// they were given.
+type Multi []PackageIndex
+
+// PartialError comes back from Multi.Versions along with the versions
+// when some of the indexes failed and others answered. The versions can
+// be used, they're only missing whatever the failed indexes have.
+type PartialError struct {
+	// what each failed index said, in priority order
+	Errs []error
+}
+
+func (e *PartialError) Error() string {
+	msgs := []string{}
+	for _, err := range e.Errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *PartialError) Unwrap() []error {
+	return e.Errs
+}
+
+// Config is what the index clients get built with.
+type Config struct {
+	// credentials by host
+	Auth AuthConfig
+	// nil means no caching
+	Cache *Cache
+	// hosts, or host:port, to skip TLS verification for, like pip's
+	// --trusted-host
+	TrustedHosts []string
+	// the --trusted-host options of the requirements file. They don't
+	// count for hosts with credentials in Auth, an uploaded file mustn't
+	// get those sent over a connection that isn't verified.
+	FileTrustedHosts []string
+}
+
+// ForURLs builds the index for a list of pip index urls, in priority
+// order. PyPI itself gets the richer JSON API, anything else gets the
+// Simple API. Credentials come from the url or from config.Auth.
+func ForURLs(indexURLs []string, config Config) PackageIndex {
+	multi := Multi{}
+	for _, indexURL := range indexURLs {
+		multi = append(multi, ForURL(indexURL, config))
+	}
+	if len(multi) == 1 {
+		return multi[0]
+	}
+	return multi
+}
+
+func ForURL(indexURL string, config Config) PackageIndex {
+	indexURL, creds := config.Auth.forURL(indexURL)
+	parsed, err := url.Parse(indexURL)
+	if err == nil && creds == nil && warehouseHosts[parsed.Hostname()] {
+		pypi := NewPyPI(indexURL, config.Cache)
+		if config.trusted(parsed) {
+			pypi.Client = insecureClient
+		}
+		return pypi
+	}
+	simple := NewSimple(indexURL, creds, config.Cache)
+	if err == nil && config.trusted(parsed) {
+		simple.Client = insecureClient
+	}
+	return simple
+}
+
+// trusted matches the url against TrustedHosts, and FileTrustedHosts
+// when Auth has nothing for the host. A host without a port trusts every
+// port on it like it does for pip.
+func (config Config) trusted(indexURL *url.URL) bool {
+	hosts := config.TrustedHosts
+	if _, ok := config.Auth[indexURL.Hostname()]; !ok {
+		hosts = append(slices.Clone(hosts), config.FileTrustedHosts...)
+	}
+	for _, host := range hosts {
+		if strings.EqualFold(host, indexURL.Host) || strings.EqualFold(host, indexURL.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+// insecureClient is for trusted hosts, which pip talks to even when
+// their certificate doesn't check out
+var insecureClient = &http.Client{Transport: &http.Transport{
+	Proxy:           http.ProxyFromEnvironment,
+	TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+}}
+
+// the hosts we know serve the warehouse JSON API
+var warehouseHosts = map[string]bool{
+	"pypi.org":      true,
+	"test.pypi.org": true,
+}
+
+func (m Multi) Versions(ctx context.Context, name string) ([]Version, error) {
+	if len(m) == 0 {
+		return nil, ErrNoIndex
+	}
+	type answer struct {
+		versions []Version
+		err      error
+	}
+	answers := make([]answer, len(m))
+	var wg sync.WaitGroup
+	for i, idx := range m {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			answers[i].versions, answers[i].err = idx.Versions(ctx, name)
+		}()
+	}
+	wg.Wait()
+
+	versions := []Version{}
+	seen := map[string]bool{}
+	failed := []error{}
+	found := false
+	for _, answer := range answers {
+		if answer.err != nil {
+			// a missing package on one index is normal, anything worse
+			// is worth knowing about
+			if !errors.Is(answer.err, ErrNotFound) {
+				failed = append(failed, answer.err)
+			}
+			continue
+		}
+		found = true
+		// the first index to list a version decides if it's yanked
+		for _, version := range answer.versions {
+			if !seen[version.Version] {
+				seen[version.Version] = true
+				versions = append(versions, version)
+			}
+		}
+	}
+	switch {
+	case !found && len(failed) > 0:
+		return nil, errors.Join(failed...)
+	case !found:
+		return nil, ErrNotFound
+	case len(failed) > 0:
+		return versions, &PartialError{Errs: failed}
+	}
+	return versions, nil
+}
+
+func (m Multi) Release(ctx context.Context, name string, version string) (*Release, error) {
+	return first(m, func(idx PackageIndex) (*Release, error) {
+		return idx.Release(ctx, name, version)
+	})
+}
+
+func (m Multi) Files(ctx context.Context, name string, version string) ([]File, error) {
+	return first(m, func(idx PackageIndex) ([]File, error) {
+		return idx.Files(ctx, name, version)
+	})
+}
+
+// first gives back the answer of the first index that has one
+func first[T any](m Multi, lookup func(PackageIndex) (T, error)) (T, error) {
+	var zero T
+	var lastErr error = ErrNotFound
+	for _, idx := range m {
+		got, err := lookup(idx)
+		if err == nil {
+			return got, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			lastErr = err
+		}
+	}
+	return zero, lastErr
+}
@@ -0,0 +1,132 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestTrustedHosts(t *testing.T) {
+	config := Config{TrustedHosts: []string{"pypi.internal", "mirror.example.com:8443"}}
+	tests := []struct {
+		indexURL string
+		want     bool
+	}{
+		{"https://pypi.internal/simple", true},
+		{"https://PyPI.internal:8080/simple", true},
+		{"https://mirror.example.com:8443/simple", true},
+		{"https://mirror.example.com/simple", false},
+		{"https://pypi.org/simple", false},
+	}
+	for _, test := range tests {
+		parsed, _ := url.Parse(test.indexURL)
+		if got := config.trusted(parsed); got != test.want {
+			t.Errorf("trusted(%q) = %v, want %v", test.indexURL, got, test.want)
+		}
+	}
+
+	simple := ForURL("https://pypi.internal/simple", config).(*Simple)
+	if simple.Client != insecureClient {
+		t.Errorf("ForURL() gave a trusted host the default client")
+	}
+	simple = ForURL("https://other.internal/simple", config).(*Simple)
+	if simple.Client == insecureClient {
+		t.Errorf("ForURL() skipped certificate checks for an untrusted host")
+	}
+
+	// a file can't turn off the checks for a host the server logs in to
+	config = Config{
+		Auth:             AuthConfig{"private.internal": {Token: "secret"}},
+		FileTrustedHosts: []string{"private.internal", "mirror.internal"},
+	}
+	simple = ForURL("https://private.internal/simple", config).(*Simple)
+	if simple.Client == insecureClient || simple.Auth == nil {
+		t.Errorf("ForURL() trusted a host with credentials because the file said so")
+	}
+	simple = ForURL("https://mirror.internal/simple", config).(*Simple)
+	if simple.Client != insecureClient {
+		t.Errorf("ForURL() ignored the file's --trusted-host for a host without credentials")
+	}
+}
+
+// failingIndex answers every lookup with err.
+type failingIndex struct {
+	PackageIndex
+	err error
+}
+
+func (idx failingIndex) Versions(ctx context.Context, name string) ([]Version, error) {
+	return nil, idx.err
+}
+
+// waitingIndex only answers once every index in the group was asked.
+type waitingIndex struct {
+	Memory
+	asked chan struct{}
+	group int
+}
+
+func (idx waitingIndex) Versions(ctx context.Context, name string) ([]Version, error) {
+	idx.asked <- struct{}{}
+	for len(idx.asked) < idx.group {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+	return idx.Memory.Versions(ctx, name)
+}
+
+func TestMultiVersions(t *testing.T) {
+	project := func(versions ...string) Memory {
+		releases := map[string]*ProjectRelease{}
+		for _, v := range versions {
+			releases[v] = &ProjectRelease{}
+		}
+		return Memory{"pkg": {Name: "pkg", Releases: releases}}
+	}
+	down := errors.New("unexpected status 503 from https://mirror.example.com/simple/pkg/")
+
+	// every index is asked at once, one after another would never finish
+	asked := make(chan struct{}, 2)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	multi := Multi{waitingIndex{project("1.0"), asked, 2}, waitingIndex{project("1.0", "2.0"), asked, 2}}
+	versions, err := multi.Versions(ctx, "pkg")
+	if err != nil || len(versions) != 2 {
+		t.Errorf("Versions() = %v, %v, want both indexes pooled", versions, err)
+	}
+
+	// the versions of the indexes that answered still come back
+	versions, err = Multi{failingIndex{err: down}, project("1.0"), failingIndex{err: ErrNotFound}}.Versions(context.Background(), "pkg")
+	var partial *PartialError
+	if !errors.As(err, &partial) || len(partial.Errs) != 1 || !errors.Is(err, down) || len(versions) != 1 {
+		t.Errorf("Versions() = %v, %v, want 1.0 and a PartialError with the failure", versions, err)
+	}
+
+	_, err = Multi{failingIndex{err: down}, failingIndex{err: ErrNotFound}}.Versions(context.Background(), "pkg")
+	if !errors.Is(err, down) || errors.As(err, &partial) {
+		t.Errorf("Versions() error = %v, want the failure when no index answered", err)
+	}
+	_, err = Multi{project("1.0"), failingIndex{err: ErrNotFound}}.Versions(context.Background(), "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Versions() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAuthOnlyOverHTTPS(t *testing.T) {
+	config := Config{Auth: AuthConfig{"private.internal": {Token: "secret"}}}
+	if simple := ForURL("https://private.internal/simple", config).(*Simple); simple.Auth == nil || simple.Auth.Token != "secret" {
+		t.Errorf("ForURL() over https has auth %+v, want the token", simple.Auth)
+	}
+	if simple := ForURL("http://private.internal/simple", config).(*Simple); simple.Auth != nil {
+		t.Errorf("ForURL() would send %+v in cleartext", simple.Auth)
+	}
+	// credentials written into the url are the file's own
+	if simple := ForURL("http://me:pw@private.internal/simple", config).(*Simple); simple.Auth == nil || simple.Auth.Username != "me" {
+		t.Errorf("ForURL() has auth %+v, want the url's", simple.Auth)
+	}
+}
@@ -0,0 +1,198 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+)
+
+// PyPI talks to the warehouse JSON API (`/pypi/<name>/json`) that
+// pypi.org and its clones serve.
+type PyPI struct {
+	BaseURL string
+	Client  *http.Client
+	Cache   *Cache
+
+	// project documents already fetched, a single check asks for the
+	// same project several times
+	mu       sync.Mutex
+	projects map[string]*pypiProject
+}
+
+// NewPyPI builds a client from a pip style index url, so
+// `https://pypi.org/simple` talks to `https://pypi.org/pypi/...`.
+func NewPyPI(indexURL string, cache *Cache) *PyPI {
+	base := strings.TrimSuffix(strings.TrimSuffix(indexURL, "/"), "/simple")
+	return &PyPI{BaseURL: base, Client: http.DefaultClient, Cache: cache}
+}
+
+type pypiInfo struct {
+	Name              string   `json:"name"`
+	Version           string   `json:"version"`
+	Summary           string   `json:"summary"`
+	Description       string   `json:"description"`
+	RequiresDist      []string `json:"requires_dist"`
+	RequiresPython    string   `json:"requires_python"`
+	License           string   `json:"license"`
+	LicenseExpression string   `json:"license_expression"`
+	Classifiers       []string `json:"classifiers"`
+	Yanked            bool     `json:"yanked"`
+	YankedReason      string   `json:"yanked_reason"`
+}
+
+type pypiFile struct {
+	Filename       string            `json:"filename"`
+	URL            string            `json:"url"`
+	Digests        map[string]string `json:"digests"`
+	RequiresPython string            `json:"requires_python"`
+	Yanked         bool              `json:"yanked"`
+	YankedReason   string            `json:"yanked_reason"`
+	UploadTime     string            `json:"upload_time_iso_8601"`
+	PackageType    string            `json:"packagetype"`
+}
+
+type pypiProject struct {
+	Info     pypiInfo              `json:"info"`
+	Releases map[string][]pypiFile `json:"releases"`
+	URLs     []pypiFile            `json:"urls"`
+}
+
+func (f pypiFile) toFile() File {
+	uploaded, _ := time.Parse(time.RFC3339, f.UploadTime)
+	return File{
+		Filename:       f.Filename,
+		URL:            f.URL,
+		Digests:        f.Digests,
+		RequiresPython: f.RequiresPython,
+		Yanked:         f.Yanked,
+		YankedReason:   f.YankedReason,
+		UploadTime:     uploaded,
+		PackageType:    f.PackageType,
+	}
+}
+
+func (p *PyPI) client() *http.Client {
+	if p.Client == nil {
+		return http.DefaultClient
+	}
+	return p.Client
+}
+
+func (p *PyPI) get(ctx context.Context, name string, url string, into interface{}) error {
+	resp, err := p.Cache.get(ctx, p.client(), name, url, func(req *http.Request) {
+		req.Header.Set("Accept", "application/json")
+	})
+	if err != nil {
+		return err
+	}
+
+	if resp.Status == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.Status != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.Status, url)
+	}
+	if err := json.Unmarshal(resp.Body, into); err != nil {
+		return fmt.Errorf("error parsing JSON response from %s: %v", url, err)
+	}
+	return nil
+}
+
+func (p *PyPI) project(ctx context.Context, name string) (*pypiProject, error) {
+	name = pep508.NormalizeName(name)
+	p.mu.Lock()
+	cached, ok := p.projects[name]
+	p.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	var project pypiProject
+	if err := p.get(ctx, name, fmt.Sprintf("%s/pypi/%s/json", p.BaseURL, name), &project); err != nil {
+		return nil, err
+	}
+	if project.Releases == nil {
+		return nil, ErrNotFound
+	}
+
+	p.mu.Lock()
+	if p.projects == nil {
+		p.projects = map[string]*pypiProject{}
+	}
+	p.projects[name] = &project
+	p.mu.Unlock()
+	return &project, nil
+}
+
+func (p *PyPI) Versions(ctx context.Context, name string) ([]Version, error) {
+	project, err := p.project(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	versions := []Version{}
+	for version, uploaded := range project.Releases {
+		files := []File{}
+		for _, file := range uploaded {
+			files = append(files, file.toFile())
+		}
+		versions = append(versions, versionOf(version, files))
+	}
+	return versions, nil
+}
+
+func (p *PyPI) Release(ctx context.Context, name string, version string) (*Release, error) {
+	name = pep508.NormalizeName(name)
+	var project pypiProject
+	if err := p.get(ctx, name, fmt.Sprintf("%s/pypi/%s/%s/json", p.BaseURL, name, version), &project); err != nil {
+		return nil, err
+	}
+	info := project.Info
+	release := &Release{
+		Name:              info.Name,
+		Version:           info.Version,
+		Summary:           info.Summary,
+		Description:       info.Description,
+		RequiresDist:      info.RequiresDist,
+		RequiresPython:    info.RequiresPython,
+		License:           info.License,
+		LicenseExpression: info.LicenseExpression,
+		Classifiers:       info.Classifiers,
+		Yanked:            info.Yanked,
+		YankedReason:      info.YankedReason,
+	}
+	// the yanked flag is only on the files for older warehouse versions,
+	// a release is yanked once every one of its files is
+	if !release.Yanked && len(project.URLs) > 0 {
+		allYanked := true
+		for _, file := range project.URLs {
+			allYanked = allYanked && file.Yanked
+		}
+		if allYanked {
+			release.Yanked = true
+			release.YankedReason = project.URLs[0].YankedReason
+		}
+	}
+	return release, nil
+}
+
+func (p *PyPI) Files(ctx context.Context, name string, version string) ([]File, error) {
+	project, err := p.project(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	uploaded, ok := project.Releases[version]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	files := []File{}
+	for _, file := range uploaded {
+		files = append(files, file.toFile())
+	}
+	return files, nil
+}
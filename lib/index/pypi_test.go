@@ -0,0 +1,76 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPyPI(t *testing.T) {
+	var projectRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pypi/demo-pkg/json":
+			projectRequests.Add(1)
+			w.Write([]byte(`{"info": {"name": "Demo_Pkg", "version": "1.1"}, "releases": {
+				"1.0": [{"filename": "demo_pkg-1.0.tar.gz", "digests": {"sha256": "abc"}, "packagetype": "sdist",
+				         "upload_time_iso_8601": "2023-04-05T06:07:08.123456Z"}],
+				"1.1": [{"filename": "demo_pkg-1.1-py3-none-any.whl", "packagetype": "bdist_wheel", "requires_python": ">=3.9"}]
+			}}`))
+		case "/pypi/demo-pkg/1.0/json":
+			// older warehouses only mark the files yanked
+			w.Write([]byte(`{"info": {"name": "Demo_Pkg", "version": "1.0", "requires_dist": ["six"], "license_expression": "MIT"},
+				"urls": [{"filename": "demo_pkg-1.0.tar.gz", "yanked": true, "yanked_reason": "broken"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	pypi := NewPyPI(server.URL+"/simple/", nil)
+
+	versions, err := pypi.Versions(ctx, "Demo_Pkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, v := range versions {
+		got = append(got, v.Version)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"1.0", "1.1"}) {
+		t.Errorf("Versions() = %v, want 1.0 and 1.1", got)
+	}
+
+	files, err := pypi.Files(ctx, "demo.pkg", "1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Digests["sha256"] != "abc" || files[0].PackageType != "sdist" || files[0].UploadTime.Year() != 2023 {
+		t.Errorf("Files(1.0) = %+v", files)
+	}
+	if _, err := pypi.Files(ctx, "demo-pkg", "2.0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Files(2.0) error = %v, want ErrNotFound", err)
+	}
+	// the project document is fetched once per client
+	if n := projectRequests.Load(); n != 1 {
+		t.Errorf("fetched the project %d times, want 1", n)
+	}
+
+	release, err := pypi.Release(ctx, "demo-pkg", "1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !release.Yanked || release.YankedReason != "broken" || release.LicenseExpression != "MIT" || !slices.Equal(release.RequiresDist, []string{"six"}) {
+		t.Errorf("Release(1.0) = %+v", release)
+	}
+
+	if _, err := pypi.Versions(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Versions(missing) error = %v, want ErrNotFound", err)
+	}
+}
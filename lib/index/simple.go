@@ -0,0 +1,385 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+)
+
+const (
+	simpleJSONType = "application/vnd.pypi.simple.v1+json"
+	// ask for JSON (PEP 691) but take HTML (PEP 503) from older servers
+	simpleAccept = simpleJSONType + ", application/vnd.pypi.simple.v1+html;q=0.2, text/html;q=0.1"
+)
+
+// Simple talks to the Simple repository API that every index speaks,
+// devpi, Artifactory, Nexus and friends included. It prefers the PEP 691
+// JSON form and falls back to parsing the PEP 503 HTML page.
+type Simple struct {
+	IndexURL string
+	Auth     *Auth
+	Client   *http.Client
+	Cache    *Cache
+
+	mu       sync.Mutex
+	projects map[string][]simpleFile
+}
+
+func NewSimple(indexURL string, auth *Auth, cache *Cache) *Simple {
+	return &Simple{IndexURL: strings.TrimSuffix(indexURL, "/"), Auth: auth, Client: http.DefaultClient, Cache: cache}
+}
+
+// simpleFile is a File plus what we need to find its metadata
+type simpleFile struct {
+	File
+	Version string
+	// PEP 658, the METADATA file is served at the file url + ".metadata"
+	HasMetadata bool
+}
+
+type simpleJSONProject struct {
+	Name  string `json:"name"`
+	Files []struct {
+		Filename       string            `json:"filename"`
+		URL            string            `json:"url"`
+		Hashes         map[string]string `json:"hashes"`
+		RequiresPython string            `json:"requires-python"`
+		// false, true or the reason it was yanked
+		Yanked           json.RawMessage `json:"yanked"`
+		CoreMetadata     json.RawMessage `json:"core-metadata"`
+		DistInfoMetadata json.RawMessage `json:"dist-info-metadata"`
+		UploadTime       string          `json:"upload-time"`
+	} `json:"files"`
+}
+
+func (s *Simple) client() *http.Client {
+	if s.Client == nil {
+		return http.DefaultClient
+	}
+	return s.Client
+}
+
+func (s *Simple) fetch(ctx context.Context, name string, pageURL string, accept string) (*response, error) {
+	resp, err := s.Cache.get(ctx, s.client(), name, pageURL, func(req *http.Request) {
+		req.Header.Set("Accept", accept)
+		// file urls often point at a CDN or bucket somewhere else, like
+		// pip only the index itself gets the credentials
+		if sameOrigin(req.URL, s.IndexURL) {
+			s.Auth.apply(req)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.Status != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.Status, pageURL)
+	}
+	return resp, nil
+}
+
+func sameOrigin(target *url.URL, indexURL string) bool {
+	index, err := url.Parse(indexURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(target.Scheme, index.Scheme) && strings.EqualFold(target.Host, index.Host)
+}
+
+func (s *Simple) project(ctx context.Context, name string) ([]simpleFile, error) {
+	name = pep508.NormalizeName(name)
+	s.mu.Lock()
+	cached, ok := s.projects[name]
+	s.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	pageURL := fmt.Sprintf("%s/%s/", s.IndexURL, name)
+	resp, err := s.fetch(ctx, name, pageURL, simpleAccept)
+	if err != nil {
+		return nil, err
+	}
+
+	base, _ := url.Parse(pageURL)
+	var files []simpleFile
+	if strings.HasPrefix(resp.ContentType, simpleJSONType) {
+		files, err = parseSimpleJSON(bytes.NewReader(resp.Body), base)
+	} else {
+		files, err = parseSimpleHTML(bytes.NewReader(resp.Body), base)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing project page %s: %v", pageURL, err)
+	}
+	for i := range files {
+		files[i].Version = versionFromFilename(files[i].Filename, name)
+	}
+
+	s.mu.Lock()
+	if s.projects == nil {
+		s.projects = map[string][]simpleFile{}
+	}
+	s.projects[name] = files
+	s.mu.Unlock()
+	return files, nil
+}
+
+func parseSimpleJSON(r io.Reader, base *url.URL) ([]simpleFile, error) {
+	var project simpleJSONProject
+	if err := json.NewDecoder(r).Decode(&project); err != nil {
+		return nil, err
+	}
+	files := []simpleFile{}
+	for _, f := range project.Files {
+		file := simpleFile{File: File{
+			Filename:       f.Filename,
+			URL:            resolveURL(base, f.URL),
+			Digests:        f.Hashes,
+			RequiresPython: f.RequiresPython,
+		}}
+		file.Yanked, file.YankedReason = parseYanked(f.Yanked)
+		file.HasMetadata = hasMetadata(f.CoreMetadata) || hasMetadata(f.DistInfoMetadata)
+		file.UploadTime, _ = time.Parse(time.RFC3339, f.UploadTime)
+		file.PackageType = packageType(f.Filename)
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// yanked is either a bool or the reason as a string
+func parseYanked(raw json.RawMessage) (bool, string) {
+	var yanked bool
+	if json.Unmarshal(raw, &yanked) == nil {
+		return yanked, ""
+	}
+	var reason string
+	if json.Unmarshal(raw, &reason) == nil {
+		return true, reason
+	}
+	return false, ""
+}
+
+// metadata is either a bool or a map of hashes of the metadata file
+func hasMetadata(raw json.RawMessage) bool {
+	var present bool
+	if json.Unmarshal(raw, &present) == nil {
+		return present
+	}
+	var hashes map[string]string
+	return json.Unmarshal(raw, &hashes) == nil
+}
+
+var (
+	anchorRe    = regexp.MustCompile(`(?is)<a\s([^>]*)>(.*?)</a>`)
+	attributeRe = regexp.MustCompile(`(?s)([a-zA-Z0-9_-]+)\s*=\s*("[^"]*"|'[^']*')`)
+)
+
+func parseSimpleHTML(r io.Reader, base *url.URL) ([]simpleFile, error) {
+	page, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	files := []simpleFile{}
+	for _, anchor := range anchorRe.FindAllStringSubmatch(string(page), -1) {
+		attrs := map[string]string{}
+		for _, attr := range attributeRe.FindAllStringSubmatch(anchor[1], -1) {
+			attrs[strings.ToLower(attr[1])] = html.UnescapeString(strings.Trim(attr[2], `"'`))
+		}
+		href, ok := attrs["href"]
+		if !ok {
+			continue
+		}
+
+		fileURL := resolveURL(base, href)
+		file := simpleFile{File: File{
+			Filename:       strings.TrimSpace(html.UnescapeString(anchor[2])),
+			URL:            fileURL,
+			RequiresPython: attrs["data-requires-python"],
+			Digests:        map[string]string{},
+		}}
+		// hashes ride along in the url fragment, `#sha256=...`
+		if parsed, err := url.Parse(fileURL); err == nil && parsed.Fragment != "" {
+			if algo, digest, ok := strings.Cut(parsed.Fragment, "="); ok {
+				file.Digests[algo] = digest
+			}
+			parsed.Fragment = ""
+			file.URL = parsed.String()
+		}
+		if reason, ok := attrs["data-yanked"]; ok {
+			file.Yanked, file.YankedReason = true, reason
+		}
+		_, coreMetadata := attrs["data-core-metadata"]
+		_, distInfoMetadata := attrs["data-dist-info-metadata"]
+		file.HasMetadata = coreMetadata || distInfoMetadata
+		file.PackageType = packageType(file.Filename)
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+func resolveURL(base *url.URL, ref string) string {
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(parsed).String()
+}
+
+func packageType(filename string) string {
+	if strings.HasSuffix(filename, ".whl") {
+		return "bdist_wheel"
+	}
+	return "sdist"
+}
+
+var sdistExtensions = []string{".tar.gz", ".tar.bz2", ".tar.xz", ".tgz", ".zip"}
+
+// versionFromFilename pulls the version out of a wheel
+// (`name-1.0-py3-none-any.whl`) or sdist (`name-1.0.tar.gz`) filename.
+// Anything else, like old .egg or .exe files, gives "".
+func versionFromFilename(filename string, name string) string {
+	if strings.HasSuffix(filename, ".whl") {
+		parts := strings.Split(strings.TrimSuffix(filename, ".whl"), "-")
+		if len(parts) < 5 {
+			return ""
+		}
+		return parts[1]
+	}
+
+	base := ""
+	for _, ext := range sdistExtensions {
+		if strings.HasSuffix(filename, ext) {
+			base = strings.TrimSuffix(filename, ext)
+			break
+		}
+	}
+	if base == "" {
+		return ""
+	}
+	// the name part can have dashes of its own, so find where the
+	// normalized name ends
+	for i := 0; i < len(base); i++ {
+		if base[i] == '-' && pep508.NormalizeName(base[:i]) == name {
+			return base[i+1:]
+		}
+	}
+	if i := strings.LastIndex(base, "-"); i >= 0 {
+		return base[i+1:]
+	}
+	return ""
+}
+
+func (s *Simple) Versions(ctx context.Context, name string) ([]Version, error) {
+	files, err := s.project(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	order := []string{}
+	byVersion := map[string][]File{}
+	for _, file := range files {
+		if file.Version == "" {
+			continue
+		}
+		if _, seen := byVersion[file.Version]; !seen {
+			order = append(order, file.Version)
+		}
+		byVersion[file.Version] = append(byVersion[file.Version], file.File)
+	}
+	versions := []Version{}
+	for _, version := range order {
+		versions = append(versions, versionOf(version, byVersion[version]))
+	}
+	return versions, nil
+}
+
+func (s *Simple) releaseFiles(ctx context.Context, name string, version string) ([]simpleFile, error) {
+	files, err := s.project(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	matched := []simpleFile{}
+	for _, file := range files {
+		if file.Version == version {
+			matched = append(matched, file)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, ErrNotFound
+	}
+	return matched, nil
+}
+
+func (s *Simple) Files(ctx context.Context, name string, version string) ([]File, error) {
+	matched, err := s.releaseFiles(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+	files := []File{}
+	for _, file := range matched {
+		files = append(files, file.File)
+	}
+	return files, nil
+}
+
+// Release reads the PEP 658 metadata file the index serves next to a
+// file. A release none of whose files has one that can be read fails
+// with ErrNoMetadata, the project page alone doesn't say what it
+// depends on.
+func (s *Simple) Release(ctx context.Context, name string, version string) (*Release, error) {
+	matched, err := s.releaseFiles(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var release *Release
+	var lastErr error
+	for _, file := range matched {
+		if !file.HasMetadata {
+			continue
+		}
+		resp, err := s.fetch(ctx, name, file.URL+".metadata", "*/*")
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		parsed, err := parseCoreMetadata(bytes.NewReader(resp.Body))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		release = parsed
+		break
+	}
+	if release == nil {
+		if lastErr != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNoMetadata, lastErr)
+		}
+		return nil, fmt.Errorf("%w, the index doesn't serve PEP 658 metadata for it", ErrNoMetadata)
+	}
+	if release.RequiresPython == "" {
+		release.RequiresPython = matched[0].RequiresPython
+	}
+
+	// a release is yanked once every one of its files is
+	release.Yanked = true
+	for _, file := range matched {
+		release.Yanked = release.Yanked && file.Yanked
+	}
+	if release.Yanked {
+		release.YankedReason = matched[0].YankedReason
+	}
+	return release, nil
+}
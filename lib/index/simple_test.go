@@ -0,0 +1,168 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseSimpleHTML(t *testing.T) {
+	page := `<html><body>
+<a href="../../files/demo-1.0.tar.gz#sha256=abc">demo-1.0.tar.gz</a>
+<a href="https://cdn.example.com/demo-1.1-py3-none-any.whl" data-requires-python="&gt;=3.8" data-core-metadata="sha256=def">demo-1.1-py3-none-any.whl</a>
+<a href='demo-1.2.zip' data-yanked="broken">demo-1.2.zip</a>
+</body></html>`
+	base, _ := url.Parse("https://index.example.com/simple/demo/")
+	files, err := parseSimpleHTML(strings.NewReader(page), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("got %d files, want 3", len(files))
+	}
+	if files[0].URL != "https://index.example.com/files/demo-1.0.tar.gz" || files[0].Digests["sha256"] != "abc" || files[0].PackageType != "sdist" {
+		t.Errorf("first file = %+v", files[0])
+	}
+	if files[1].RequiresPython != ">=3.8" || !files[1].HasMetadata || files[1].PackageType != "bdist_wheel" {
+		t.Errorf("second file = %+v", files[1])
+	}
+	if !files[2].Yanked || files[2].YankedReason != "broken" || files[2].URL != "https://index.example.com/simple/demo/demo-1.2.zip" {
+		t.Errorf("third file = %+v", files[2])
+	}
+}
+
+func TestParseSimpleJSON(t *testing.T) {
+	page := `{"name": "demo", "files": [
+		{"filename": "demo-1.0.tar.gz", "url": "demo-1.0.tar.gz", "hashes": {"sha256": "abc"}, "yanked": false},
+		{"filename": "demo-1.1-py3-none-any.whl", "url": "/f/demo-1.1-py3-none-any.whl", "hashes": {}, "requires-python": ">=3.9",
+		 "yanked": "security issue", "core-metadata": {"sha256": "def"}, "upload-time": "2024-01-02T03:04:05Z"}
+	]}`
+	base, _ := url.Parse("https://index.example.com/simple/demo/")
+	files, err := parseSimpleJSON(strings.NewReader(page), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+	if files[0].Yanked || files[0].HasMetadata || files[0].URL != "https://index.example.com/simple/demo/demo-1.0.tar.gz" {
+		t.Errorf("first file = %+v", files[0])
+	}
+	if !files[1].Yanked || files[1].YankedReason != "security issue" || !files[1].HasMetadata ||
+		files[1].URL != "https://index.example.com/f/demo-1.1-py3-none-any.whl" || files[1].UploadTime.Year() != 2024 {
+		t.Errorf("second file = %+v", files[1])
+	}
+}
+
+func TestVersionFromFilename(t *testing.T) {
+	tests := []struct {
+		filename, name, want string
+	}{
+		{"demo-1.0-py3-none-any.whl", "demo", "1.0"},
+		{"demo-1.0.tar.gz", "demo", "1.0"},
+		{"zope.interface-6.0.tar.gz", "zope-interface", "6.0"},
+		{"my-long-name-2.1rc1.zip", "my-long-name", "2.1rc1"},
+		{"demo-1.0.egg", "demo", ""},
+		{"broken.whl", "broken", ""},
+	}
+	for _, test := range tests {
+		if got := versionFromFilename(test.filename, test.name); got != test.want {
+			t.Errorf("versionFromFilename(%q, %q) = %q, want %q", test.filename, test.name, got, test.want)
+		}
+	}
+}
+
+// the index and a file host somewhere else, each remembering the
+// Authorization headers it saw
+type authRecorder struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (r *authRecorder) record(req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen = append(r.seen, req.Header.Get("Authorization"))
+}
+
+func TestSimpleAuthOnlyGoesToIndex(t *testing.T) {
+	var files, idx authRecorder
+	fileHost := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		files.record(req)
+		fmt.Fprint(w, "Metadata-Version: 2.1\nName: demo\nVersion: 1.0\nRequires-Dist: six\n")
+	}))
+	defer fileHost.Close()
+	indexHost := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		idx.record(req)
+		w.Header().Set("Content-Type", simpleJSONType)
+		fmt.Fprintf(w, `{"name": "demo", "files": [{"filename": "demo-1.0-py3-none-any.whl", "url": "%s/demo-1.0-py3-none-any.whl", "hashes": {}, "core-metadata": true}]}`, fileHost.URL)
+	}))
+	defer indexHost.Close()
+
+	simple := NewSimple(indexHost.URL+"/simple", &Auth{Token: "secret"}, nil)
+	release, err := simple.Release(context.Background(), "demo", "1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(release.RequiresDist) != 1 || release.RequiresDist[0] != "six" {
+		t.Errorf("Release() = %+v, want the metadata from the file host", release)
+	}
+	if len(idx.seen) != 1 || idx.seen[0] != "Bearer secret" {
+		t.Errorf("the index saw %q, want the token", idx.seen)
+	}
+	if len(files.seen) != 1 || files.seen[0] != "" {
+		t.Errorf("the file host saw %q, want no credentials", files.seen)
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"https://pypi.example.com/simple/demo/", true},
+		{"HTTPS://PyPI.example.com/files/demo.whl", true},
+		{"http://pypi.example.com/simple/demo/", false},
+		{"https://pypi.example.com:8443/simple/demo/", false},
+		{"https://files.example.com/demo.whl", false},
+	}
+	for _, test := range tests {
+		target, _ := url.Parse(test.target)
+		if got := sameOrigin(target, "https://pypi.example.com/simple"); got != test.want {
+			t.Errorf("sameOrigin(%q) = %v, want %v", test.target, got, test.want)
+		}
+	}
+}
+
+func TestSimpleReleaseWithoutMetadata(t *testing.T) {
+	// extra fields of the file entry, broken says there's a .metadata
+	// but it's missing
+	files := map[string]string{
+		"plain":  ``,
+		"broken": `, "core-metadata": true`,
+	}
+	for name, file := range files {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if strings.HasSuffix(req.URL.Path, ".metadata") {
+					http.NotFound(w, req)
+					return
+				}
+				w.Header().Set("Content-Type", simpleJSONType)
+				fmt.Fprintf(w, `{"name": "demo", "files": [{"filename": "demo-1.0-py3-none-any.whl", "url": "/files/demo-1.0-py3-none-any.whl", "hashes": {}%s}]}`, file)
+			}))
+			defer server.Close()
+
+			release, err := NewSimple(server.URL+"/simple", nil, nil).Release(context.Background(), "demo", "1.0")
+			if !errors.Is(err, ErrNoMetadata) {
+				t.Errorf("Release() = %+v, %v, want ErrNoMetadata", release, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	"github.com/DerekCorniello/pip-req-valid/pep440"
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+// ConfusionGuard protects internal package names from dependency
+// confusion, someone publishing the same name on the public index with
+// a higher version so pip picks theirs.
+type ConfusionGuard struct {
+	// internal package names, or globs of them like `acme-*`, matched
+	// against the normalized name
+	Internal []string
+	// where an attacker would publish, DefaultIndexURL when empty
+	PublicURL string
+	// looks packages up on PublicURL, built from it when nil
+	Public index.PackageIndex
+}
+
+// ParseInternalPackages reads a comma separated list of names and
+// globs, like `acme-*,billing-client`.
+func ParseInternalPackages(s string) ([]string, error) {
+	patterns := []string{}
+	for _, pattern := range strings.Split(s, ",") {
+		pattern = pep508.NormalizeName(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.New("invalid internal package pattern '" + pattern + "'")
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+func (g *ConfusionGuard) publicURL() string {
+	if g.PublicURL == "" {
+		return DefaultIndexURL
+	}
+	return g.PublicURL
+}
+
+func (g *ConfusionGuard) internal(name string) bool {
+	name = pep508.NormalizeName(name)
+	for _, pattern := range g.Internal {
+		if ok, _ := path.Match(pep508.NormalizeName(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func sameIndex(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
+
+// checkConfusion looks an internal package up on the public index. It
+// existing there at all is worth a warning, anyone could publish a
+// higher version tomorrow. When the file also uses the public index and
+// the newest public version the specifiers allow is at least as high
+// as the private one, pip pools the indexes and installs the public one
+// today, which is an error.
+func checkConfusion(ctx context.Context, pkg utils.Package, opts GlobalOptions, config VerifyConfig, public index.PackageIndex, diags *[]utils.Diagnostic) bool {
+	guard := config.Confusion
+	if pkg.Requirement == nil || pkg.Requirement.URL != "" || !guard.internal(pkg.Name) {
+		return true
+	}
+
+	publicVersions, err := versionsWithTimeout(ctx, config, public, pkg.CanonicalName())
+	if errors.Is(err, index.ErrNotFound) {
+		return true
+	} else if err != nil {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityWarning, utils.CodeIndexError,
+			"Could not check whether internal package '%s' exists on %s: %v", pkg.Name, guard.publicURL(), err))
+		return true
+	}
+
+	// the indexes the file uses other than the public one
+	consulted := false
+	private := index.Multi{}
+	for _, indexURL := range opts.IndexURLs() {
+		if sameIndex(indexURL, guard.publicURL()) {
+			consulted = true
+		} else {
+			private = append(private, index.ForURL(indexURL, opts.indexConfig(config.IndexConfig)))
+		}
+	}
+	if config.Index != nil {
+		private = index.Multi{config.Index}
+	}
+	if !consulted {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityWarning, utils.CodeDependencyConfusion,
+			"Internal package '%s' also exists on %s. This file doesn't use that index, but anything installing '%s' without --index-url would get the public one.",
+			pkg.Name, guard.publicURL(), pkg.Name))
+		return true
+	}
+
+	bestPublic := newest(allowedVersions(pkg, publicVersions, opts))
+	var bestPrivate *pep440.Version
+	if len(private) > 0 {
+		// what the private indexes that answered have is still worth
+		// comparing
+		var partial *index.PartialError
+		if privateVersions, err := versionsWithTimeout(ctx, config, private, pkg.CanonicalName()); err == nil || errors.As(err, &partial) {
+			bestPrivate = newest(allowedVersions(pkg, privateVersions, opts))
+		}
+	}
+
+	switch {
+	case bestPublic == nil:
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityWarning, utils.CodeDependencyConfusion,
+			"Internal package '%s' also exists on %s. None of its public versions satisfy the specifiers today, but a new upload could.",
+			pkg.Name, guard.publicURL()))
+		return true
+	case bestPrivate == nil:
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeDependencyConfusion,
+			"No private version of internal package '%s' satisfies the specifiers but %s has one, pip would install the public %s.",
+			pkg.Name, guard.publicURL(), bestPublic.Original()))
+		return false
+	case !bestPublic.LessThan(bestPrivate):
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeDependencyConfusion,
+			"Internal package '%s' has version %s on %s, which outranks the private %s; pip pools every index and would install the public one.",
+			pkg.Name, bestPublic.Original(), guard.publicURL(), bestPrivate.Original()))
+		return false
+	}
+	*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityWarning, utils.CodeDependencyConfusion,
+		"Internal package '%s' also exists on %s (%s). The private %s still outranks it, but anyone can publish a higher version there; register the name yourself or stop using %s in this file.",
+		pkg.Name, guard.publicURL(), bestPublic.Original(), bestPrivate.Original(), guard.publicURL()))
+	return true
+}
+
+func versionsWithTimeout(ctx context.Context, config VerifyConfig, idx index.PackageIndex, name string) ([]index.Version, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.requestTimeout())
+	defer cancel()
+	return idx.Versions(ctx, name)
+}
+
+func newest(versions []*pep440.Version) *pep440.Version {
+	if len(versions) == 0 {
+		return nil
+	}
+	pep440.Sort(versions)
+	return versions[len(versions)-1]
+}
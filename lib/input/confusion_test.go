@@ -0,0 +1,76 @@
+package input
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+func TestCheckConfusion(t *testing.T) {
+	releases := func(name string, versions ...string) *index.Project {
+		project := &index.Project{Name: name, Releases: map[string]*index.ProjectRelease{}}
+		for _, v := range versions {
+			project.Releases[v] = &index.ProjectRelease{}
+		}
+		return project
+	}
+	private := index.Memory{
+		"acme-billing": releases("acme-billing", "1.0", "2.0"),
+		"acme-auth":    releases("acme-auth", "1.0"),
+		"acme-new":     releases("acme-new", "0.1"),
+	}
+	public := index.Memory{
+		"acme-billing": releases("acme-billing", "1.5"),
+		"acme-auth":    releases("acme-auth", "99.0"),
+		"acme-new":     releases("acme-new", "3.0"),
+	}
+	config := VerifyConfig{Index: private, Confusion: &ConfusionGuard{Internal: []string{"acme-*"}, Public: public}}
+
+	tests := []struct {
+		content  string
+		ok       bool
+		severity utils.Severity
+		message  string
+	}{
+		{content: "acme-billing", ok: true, severity: utils.SeverityWarning, message: "still outranks"},
+		{content: "acme-auth", ok: false, severity: utils.SeverityError, message: "outranks the private 1.0"},
+		{content: "acme-new>=1.0", ok: false, severity: utils.SeverityError, message: "No private version"},
+		{content: "acme-billing<1.5", ok: true, severity: utils.SeverityWarning, message: "None of its public versions"},
+		{content: "--index-url https://pypi.acme.internal/simple\nacme-auth", ok: true, severity: utils.SeverityWarning, message: "doesn't use that index"},
+		// not internal, or not on the public index at all
+		{content: "requests", ok: true},
+		{content: "acme-secret", ok: true},
+	}
+	for _, test := range tests {
+		reqFile, _ := ParseFile("requirements.txt", []byte(test.content), nil, nil)
+		diags := []utils.Diagnostic{}
+		ok := checkConfusion(context.Background(), reqFile.Requirements[0], reqFile.Options, config, public, &diags)
+		if ok != test.ok {
+			t.Errorf("%q: ok = %v, want %v", test.content, ok, test.ok)
+		}
+		switch {
+		case test.message == "" && len(diags) > 0:
+			t.Errorf("%q: got %v, want nothing", test.content, diags)
+		case test.message != "" && (len(diags) != 1 || diags[0].Severity != test.severity || diags[0].Code != utils.CodeDependencyConfusion ||
+			!strings.Contains(diags[0].Message, test.message)):
+			t.Errorf("%q: got %v, want one RQ027 saying %q", test.content, diags, test.message)
+		}
+	}
+}
+
+func TestParseInternalPackages(t *testing.T) {
+	patterns, err := ParseInternalPackages(" Acme_* , billing.client,,")
+	if err != nil || len(patterns) != 2 || patterns[0] != "acme-*" || patterns[1] != "billing-client" {
+		t.Errorf("ParseInternalPackages() = %v, %v", patterns, err)
+	}
+	if _, err := ParseInternalPackages("acme-[*"); err == nil {
+		t.Errorf("ParseInternalPackages() should reject a broken glob")
+	}
+	guard := &ConfusionGuard{Internal: patterns}
+	if !guard.internal("ACME.Payments") || !guard.internal("Billing_Client") || guard.internal("billing") {
+		t.Errorf("internal() doesn't match the normalized names")
+	}
+}
@@ -0,0 +1,160 @@
+package input
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+// Document is a requirements file kept exactly as it was written, line
+// endings, comments, continuations and all, so it can be edited in
+// place and written back without touching anything else.
+type Document struct {
+	Name string
+	// the physical lines, each with its line ending
+	lines []string
+}
+
+// NewDocument keeps the content of a file for editing, Bytes gives it
+// back unchanged until something is replaced.
+func NewDocument(name string, content []byte) *Document {
+	doc := &Document{Name: name}
+	text := string(content)
+	for text != "" {
+		end := strings.IndexByte(text, '\n') + 1
+		if end == 0 {
+			end = len(text)
+		}
+		doc.lines = append(doc.lines, text[:end])
+		text = text[end:]
+	}
+	return doc
+}
+
+func (doc *Document) Bytes() []byte {
+	return []byte(strings.Join(doc.lines, ""))
+}
+
+// locate is the physical line a span is on and where it starts and
+// ends in it, false when the span isn't in the file.
+func (doc *Document) locate(pos utils.Position) (line, start, end int, ok bool) {
+	line, start, end = pos.Line-1, pos.Column-1, pos.EndColumn-1
+	if line < 0 || line >= len(doc.lines) || start < 0 || end < start {
+		return 0, 0, 0, false
+	}
+	text := strings.TrimSuffix(strings.TrimSuffix(doc.lines[line], "\n"), "\r")
+	return line, start, end, end <= len(text)
+}
+
+// Text is what the file has at a span, false when the span isn't in it.
+func (doc *Document) Text(pos utils.Position) (string, bool) {
+	line, start, end, ok := doc.locate(pos)
+	if !ok {
+		return "", false
+	}
+	return doc.lines[line][start:end], true
+}
+
+// Replace swaps the text at a span for another, false when the span
+// isn't in the file.
+func (doc *Document) Replace(pos utils.Position, text string) bool {
+	line, start, end, ok := doc.locate(pos)
+	if !ok {
+		return false
+	}
+	doc.lines[line] = doc.lines[line][:start] + text + doc.lines[line][end:]
+	return true
+}
+
+// Fix is one edit FixFiles made.
+type Fix struct {
+	utils.Position
+	Code utils.Code `json:"code"`
+	Old  string     `json:"old"`
+	New  string     `json:"new"`
+}
+
+// the spans fixes are allowed to replace, anything else in the span
+// means it moved, usually because of a `${VAR}`
+var fixableText = map[utils.Code]*regexp.Regexp{
+	utils.CodeNonCanonicalName:       regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?$`),
+	utils.CodePossibleTyposquat:      regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?$`),
+	utils.CodeUnsatisfiableSpecifier: regexp.MustCompile(`^[A-Za-z0-9._+!-]+$`),
+}
+
+// FixFiles applies the fixes diagnostics suggest to the documents they
+// point into: the popular name for a typo, the canonical name, and the
+// closest release for a pin that doesn't exist. A typo only gets renamed
+// when the name isn't on the index, one that is could be a real package
+// that just looks like a popular one, and the other fixes on a renamed
+// requirement are dropped since they were about the old name. A span
+// that doesn't hold what the diagnostic was about is left alone. Docs go
+// in the order the files were parsed in, and the fixes come back in that
+// order.
+func FixFiles(docs []*Document, diags []utils.Diagnostic) []Fix {
+	byName := map[string]*Document{}
+	order := utils.FileOrder{}
+	for _, doc := range docs {
+		byName[doc.Name] = doc
+		order = append(order, doc.Name)
+	}
+
+	// requirements are keyed by where they start, the name does too
+	start := func(pos utils.Position) utils.Position {
+		return utils.Position{File: pos.File, Line: pos.Line, Column: pos.Column}
+	}
+	missing := map[utils.Position]bool{}
+	for _, diag := range diags {
+		if diag.Code == utils.CodeUnknownPackage {
+			missing[start(diag.Position)] = true
+		}
+	}
+	renamed := map[utils.Position]bool{}
+	for _, diag := range diags {
+		if diag.Fix != "" && diag.Code == utils.CodePossibleTyposquat && missing[start(diag.Position)] {
+			renamed[utils.Position{File: diag.File, Line: diag.Line}] = true
+		}
+	}
+
+	edits := []utils.Diagnostic{}
+	for _, diag := range diags {
+		if diag.Fix == "" || fixableText[diag.Code] == nil || byName[diag.File] == nil {
+			continue
+		}
+		if diag.Code == utils.CodePossibleTyposquat {
+			if !missing[start(diag.Position)] {
+				continue
+			}
+		} else if renamed[utils.Position{File: diag.File, Line: diag.Line}] {
+			continue
+		}
+		edits = append(edits, diag)
+	}
+	// back to front, so earlier columns on a line stay where they are
+	utils.SortByPosition(edits, order, func(diag utils.Diagnostic) utils.Position { return diag.Position })
+	slices.Reverse(edits)
+
+	fixes := []Fix{}
+	for i, diag := range edits {
+		// the same span twice, or overlapping the edit after it
+		if i > 0 && edits[i-1].File == diag.File && edits[i-1].Line == diag.Line && edits[i-1].Column < diag.EndColumn {
+			continue
+		}
+		doc := byName[diag.File]
+		old, ok := doc.Text(diag.Position)
+		if !ok || !fixableText[diag.Code].MatchString(old) || old == diag.Fix {
+			continue
+		}
+		// the canonical spelling has to be the same package
+		if diag.Code == utils.CodeNonCanonicalName && pep508.NormalizeName(old) != pep508.NormalizeName(diag.Fix) {
+			continue
+		}
+		doc.Replace(diag.Position, diag.Fix)
+		fixes = append(fixes, Fix{Position: diag.Position, Code: diag.Code, Old: old, New: diag.Fix})
+	}
+	slices.Reverse(fixes)
+	return fixes
+}
@@ -0,0 +1,87 @@
+package input
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	"github.com/DerekCorniello/pip-req-valid/typosquat"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+func TestDocumentRoundTrip(t *testing.T) {
+	for _, content := range []string{
+		"",
+		"requests\n",
+		"# comment\r\nnumpy==1.0  # pinned\r\n\r\nflask",
+		"foo \\\n  ==1.0 \\\n  --hash=sha256:abc\n",
+	} {
+		if got := string(NewDocument("requirements.txt", []byte(content)).Bytes()); got != content {
+			t.Errorf("NewDocument(%q).Bytes() = %q", content, got)
+		}
+	}
+}
+
+func TestFixFiles(t *testing.T) {
+	main := "# keep me\r\nPyYAML==6.0.3  # yaml\r\n-r base.txt\r\nrequest==2.0\r\nnumpy \\\r\n  ==1.26.9\r\n"
+	include := "Foo_Bar\n"
+	reqFile, parseDiags := ParseFile("requirements.txt", []byte(main), MapResolver{"base.txt": []byte(include)}, nil)
+
+	diags := append([]utils.Diagnostic{}, parseDiags...)
+	for _, pkg := range reqFile.Requirements {
+		switch pkg.Name {
+		case "PyYAML":
+			diags = append(diags, utils.Diagnostic{Position: pkg.PinPosition, Code: utils.CodeUnsatisfiableSpecifier, Fix: "6.0.2"})
+		case "numpy":
+			diags = append(diags, utils.Diagnostic{Position: pkg.PinPosition, Code: utils.CodeUnsatisfiableSpecifier, Fix: "1.26.4"})
+		case "request":
+			// without an RQ001 the name is on the index, so it stays
+			namePos := pkg.Position
+			namePos.EndColumn = namePos.Column + len("request")
+			diags = append(diags, utils.Diagnostic{Position: namePos, Code: utils.CodePossibleTyposquat, Fix: "requests"})
+		}
+	}
+
+	docs := []*Document{NewDocument("requirements.txt", []byte(main)), NewDocument("base.txt", []byte(include))}
+	fixes := FixFiles(docs, diags)
+
+	if got, want := string(docs[0].Bytes()), "# keep me\r\npyyaml==6.0.2  # yaml\r\n-r base.txt\r\nrequest==2.0\r\nnumpy \\\r\n  ==1.26.4\r\n"; got != want {
+		t.Errorf("fixed requirements.txt = %q, want %q", got, want)
+	}
+	if got, want := string(docs[1].Bytes()), "foo-bar\n"; got != want {
+		t.Errorf("fixed base.txt = %q, want %q", got, want)
+	}
+	want := []string{"PyYAML", "6.0.3", "1.26.9", "Foo_Bar"}
+	if len(fixes) != len(want) {
+		t.Fatalf("FixFiles() = %+v, want fixes of %v", fixes, want)
+	}
+	for i, fix := range fixes {
+		if fix.Old != want[i] {
+			t.Errorf("fix %d replaced %q, want %q", i, fix.Old, want[i])
+		}
+	}
+}
+
+func TestFixFilesTypo(t *testing.T) {
+	mem := index.Memory{
+		"requests": {Name: "requests", Releases: map[string]*index.ProjectRelease{"2.0": {}}},
+		"flask":    {Name: "flask", Releases: map[string]*index.ProjectRelease{"3.0": {}}},
+	}
+	config := VerifyConfig{Index: mem, PopularNames: typosquat.New([]string{"requests", "flask"})}
+	content := "# http client\r\nrequest==2.0  # typo of requests\r\n\r\nflask>=3.0 ; python_version >= '3.8'\r\n"
+
+	reqFile, parseDiags := ParseFile("requirements.txt", []byte(content), nil, nil)
+	_, _, diags := VerifyPackages(context.Background(), reqFile.Requirements, reqFile.Options, config)
+	diags = append(parseDiags, diags...)
+
+	doc := NewDocument("requirements.txt", []byte(content))
+	fixes := FixFiles([]*Document{doc}, diags)
+
+	want := "# http client\r\nrequests==2.0  # typo of requests\r\n\r\nflask>=3.0 ; python_version >= '3.8'\r\n"
+	if got := string(doc.Bytes()); got != want {
+		t.Errorf("fixed requirements.txt = %q, want %q", got, want)
+	}
+	if len(fixes) != 1 || fixes[0].Code != utils.CodePossibleTyposquat || fixes[0].Old != "request" || fixes[0].New != "requests" {
+		t.Errorf("FixFiles() = %+v, want request renamed to requests", fixes)
+	}
+}
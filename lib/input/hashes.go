@@ -0,0 +1,156 @@
+package input
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	"github.com/DerekCorniello/pip-req-valid/pep440"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+// the algorithms pip accepts in `--hash`
+var hashAlgorithms = []string{"sha256", "sha384", "sha512"}
+
+// hashCheckingMode is on with --require-hashes, and like pip, as soon
+// as any requirement has a `--hash`.
+func hashCheckingMode(packages []utils.Package, opts GlobalOptions) bool {
+	if opts.RequireHashes {
+		return true
+	}
+	for _, pkg := range packages {
+		if len(pkg.Hashes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// pinnedVersion is the version of an `==1.0` / `===1.0` pin, the only
+// kind of requirement pip takes in hash-checking mode.
+func pinnedVersion(pkg utils.Package) (string, bool) {
+	if pkg.Requirement == nil || len(pkg.Requirement.Specifiers) != 1 {
+		return "", false
+	}
+	spec := pkg.Requirement.Specifiers[0]
+	if (spec.Op != "==" && spec.Op != "===") || strings.HasSuffix(spec.Version, ".*") {
+		return "", false
+	}
+	return spec.Version, true
+}
+
+// verifyHashes checks a package the way pip's hash-checking mode will:
+// it has to be pinned, it has to have hashes, and at least one of them
+// has to match a file the index has for the pinned version.
+func verifyHashes(ctx context.Context, pkg utils.Package, lookup *versionLookup, diags *[]utils.Diagnostic) bool {
+	if pkg.Requirement != nil && pkg.Requirement.URL != "" {
+		// can't know the digest of a url without downloading it, pip
+		// checks those itself
+		if len(pkg.Hashes) == 0 {
+			*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeMissingHash,
+				"Package '%s' has no --hash, which every requirement needs in hash-checking mode.", pkg.Name))
+			return false
+		}
+		return true
+	}
+
+	version, ok := pinnedVersion(pkg)
+	if !ok {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeUnpinnedRequirement,
+			"Package '%s' must be pinned with '==' in hash-checking mode.", pkg.Name))
+		return false
+	}
+	if len(pkg.Hashes) == 0 {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeMissingHash,
+			"Package '%s' has no --hash, which every requirement needs in hash-checking mode.", pkg.Name))
+		return false
+	}
+
+	valid := true
+	declared := []string{}
+	for _, hash := range pkg.Hashes {
+		algorithm, digest, found := strings.Cut(hash, ":")
+		if !found || digest == "" || !slices.Contains(hashAlgorithms, algorithm) {
+			*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeUnknownHashAlgorithm,
+				"Hash '%s' of '%s' is not valid, it needs to be one of %s followed by ':' and the digest.", hash, pkg.Name, strings.Join(hashAlgorithms, ", ")))
+			valid = false
+			continue
+		}
+		declared = append(declared, algorithm+":"+strings.ToLower(digest))
+	}
+	if len(declared) == 0 {
+		return false
+	}
+
+	files, err := lookup.files(ctx, pkg, version)
+	if err != nil {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeIndexError,
+			"An error occurred while retrieving the files of '%s==%s': %v", pkg.Name, version, err))
+		return false
+	}
+	if len(files) == 0 {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityInfo, utils.CodeUnverifiable,
+			"The index lists no files for '%s==%s', cannot check its hashes.", pkg.Name, version))
+		return valid
+	}
+
+	published := map[string]bool{}
+	for _, file := range files {
+		for algorithm, digest := range file.Digests {
+			published[algorithm+":"+strings.ToLower(digest)] = true
+		}
+	}
+
+	matched := false
+	unmatched := []string{}
+	for _, hash := range declared {
+		algorithm, _, _ := strings.Cut(hash, ":")
+		switch {
+		case published[hash]:
+			matched = true
+		case !publishes(files, algorithm):
+			*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityInfo, utils.CodeUnverifiable,
+				"The index doesn't publish %s digests for '%s==%s', cannot check '%s'.", algorithm, pkg.Name, version, hash))
+		default:
+			unmatched = append(unmatched, hash)
+		}
+	}
+
+	if !matched && len(unmatched) > 0 {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeHashMismatch,
+			"None of the hashes of '%s' match a file of version %s, pip will refuse to install it.", pkg.Name, version))
+		return false
+	}
+	// harmless as long as another hash matches, but most likely left
+	// over from an older pin
+	for _, hash := range unmatched {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityWarning, utils.CodeHashMismatch,
+			"Hash '%s' does not match any file of '%s==%s'.", hash, pkg.Name, version))
+	}
+	return valid
+}
+
+func publishes(files []index.File, algorithm string) bool {
+	for _, file := range files {
+		if _, ok := file.Digests[algorithm]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// indexVersion finds the index's spelling of a pinned version, `1.0`
+// should find a release published as `1.0.0`.
+func indexVersion(versions []index.Version, pinned string) string {
+	want, err := pep440.Parse(pinned)
+	if err != nil {
+		return pinned
+	}
+	for _, version := range versions {
+		if v, err := pep440.Parse(version.Version); err == nil && v.Equal(want) {
+			return version.Version
+		}
+	}
+	return pinned
+}
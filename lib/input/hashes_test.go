@@ -0,0 +1,96 @@
+package input
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+func TestVerifyHashes(t *testing.T) {
+	mem := index.Memory{
+		"pkg": {Name: "pkg", Releases: map[string]*index.ProjectRelease{
+			"1.0": {Files: []index.File{
+				{Filename: "pkg-1.0.tar.gz", Digests: map[string]string{"sha256": "aaa"}},
+				{Filename: "pkg-1.0-py3-none-any.whl", Digests: map[string]string{"sha256": "bbb"}},
+			}},
+		}},
+		"other": {Name: "other", Releases: map[string]*index.ProjectRelease{
+			"2.0": {Files: []index.File{{Filename: "other-2.0.tar.gz", Digests: map[string]string{"sha256": "ccc"}}}},
+		}},
+	}
+
+	type finding struct {
+		line     int
+		severity utils.Severity
+		code     utils.Code
+	}
+	tests := []struct {
+		name     string
+		content  string
+		invalid  []string
+		findings []finding
+	}{
+		{
+			name:    "matching",
+			content: "pkg==1.0 --hash=sha256:AAA\n",
+		},
+		{
+			name:     "mismatching",
+			content:  "pkg==1.0 --hash=sha256:fff\n",
+			invalid:  []string{"pkg"},
+			findings: []finding{{1, utils.SeverityError, utils.CodeHashMismatch}},
+		},
+		{
+			name:     "one stale hash next to a match",
+			content:  "pkg==1.0 --hash=sha256:fff --hash=sha256:bbb\n",
+			findings: []finding{{1, utils.SeverityWarning, utils.CodeHashMismatch}},
+		},
+		{
+			name:     "unknown algorithm",
+			content:  "pkg==1.0 --hash=md5:aaa\n",
+			invalid:  []string{"pkg"},
+			findings: []finding{{1, utils.SeverityError, utils.CodeUnknownHashAlgorithm}},
+		},
+		{
+			name:     "digest the index doesn't publish",
+			content:  "pkg==1.0 --hash=sha512:aaa\n",
+			findings: []finding{{1, utils.SeverityInfo, utils.CodeUnverifiable}},
+		},
+		{
+			name:     "partially hashed",
+			content:  "pkg==1.0 --hash=sha256:aaa\nother==2.0\n",
+			invalid:  []string{"other"},
+			findings: []finding{{2, utils.SeverityError, utils.CodeMissingHash}},
+		},
+		{
+			name:     "require hashes",
+			content:  "--require-hashes\nother==2.0\npkg>=1.0 --hash=sha256:aaa\n",
+			invalid:  []string{"other", "pkg"},
+			findings: []finding{{2, utils.SeverityError, utils.CodeMissingHash}, {3, utils.SeverityError, utils.CodeUnpinnedRequirement}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reqFile, parseDiags := ParseFile("requirements.txt", []byte(test.content), nil, nil)
+			if len(parseDiags) > 0 {
+				t.Fatalf("ParseFile() diagnostics: %v", parseDiags)
+			}
+			_, invalid, diags := VerifyPackages(context.Background(), reqFile.Requirements, reqFile.Options, VerifyConfig{Index: mem})
+
+			if got := packageNames(invalid); !slices.Equal(got, test.invalid) {
+				t.Errorf("invalid = %v, want %v", got, test.invalid)
+			}
+			if len(diags) != len(test.findings) {
+				t.Fatalf("diagnostics = %v, want %v", diags, test.findings)
+			}
+			for i, diag := range diags {
+				if got := (finding{diag.Line, diag.Severity, diag.Code}); got != test.findings[i] {
+					t.Errorf("diagnostic %d = %v, want %v", i, diag, test.findings[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/DerekCorniello/pip-req-valid/pep440"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+const (
+	DefaultStaleAfter      = 2 * 365 * 24 * time.Hour
+	DefaultMaxMajorsBehind = 2
+)
+
+// HealthConfig sets when a project counts as unhealthy, zero values get
+// the defaults above.
+type HealthConfig struct {
+	// no release for this long means the project looks abandoned
+	StaleAfter time.Duration
+	// how many major versions the installed one can trail the latest
+	MaxMajorsBehind int
+	// what "now" is, for checking the last release, zero is time.Now
+	Now time.Time
+}
+
+func (c *HealthConfig) staleAfter() time.Duration {
+	if c.StaleAfter <= 0 {
+		return DefaultStaleAfter
+	}
+	return c.StaleAfter
+}
+
+func (c *HealthConfig) maxMajorsBehind() int {
+	if c.MaxMajorsBehind <= 0 {
+		return DefaultMaxMajorsBehind
+	}
+	return c.MaxMajorsBehind
+}
+
+func (c *HealthConfig) now() time.Time {
+	if c.Now.IsZero() {
+		return time.Now()
+	}
+	return c.Now
+}
+
+const inactiveClassifier = "Development Status :: 7 - Inactive"
+
+// how projects tend to announce they're done, the summary is short
+// enough to check for just the word
+var (
+	deprecatedSummary     = regexp.MustCompile(`(?i)\b(deprecated|unmaintained|no longer maintained|obsolete|abandoned)\b`)
+	deprecatedDescription = regexp.MustCompile(`(?i)\b(this (package|project|library|module|repository) (is|has been) (now )?(deprecated|archived|abandoned|discontinued|unmaintained)|no longer (being )?maintained|is unmaintained)\b`)
+)
+
+// only the top of a description says what the project is, changelogs
+// further down mention deprecating all sorts of things
+const descriptionHead = 1000
+
+// checkHealth gathers risk signals from the release metadata: when the
+// last upload of any version was, how many there have been, inactive and deprecated
+// notices on the latest one, and how far behind the installed version
+// is. Each signal is a warning, none of them stop it from installing.
+func checkHealth(ctx context.Context, pkg utils.Package, opts GlobalOptions, lookup *versionLookup, config *HealthConfig, diags *[]utils.Diagnostic) *utils.Health {
+	versions, installed, ok := installCandidate(ctx, pkg, opts, lookup)
+	if !ok {
+		return nil
+	}
+	stable := []*pep440.Version{}
+	for _, v := range parseVersions(versions, false) {
+		if !v.IsPrerelease() {
+			stable = append(stable, v)
+		}
+	}
+	health := &utils.Health{Version: installed.Original(), Releases: len(stable), Signals: []string{}}
+	latest := newest(stable)
+	if latest == nil {
+		latest = installed
+	}
+	health.LatestVersion = latest.Original()
+
+	// a backport to an old series is a release too, so every version
+	// counts, the indexes have all their files from the one project page
+	for _, version := range versions {
+		files, err := lookup.files(ctx, pkg, version.Version)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if !file.UploadTime.IsZero() && (health.LastRelease == nil || file.UploadTime.After(*health.LastRelease)) {
+				uploaded := file.UploadTime
+				health.LastRelease = &uploaded
+			}
+		}
+	}
+	if release, err := lookup.release(ctx, pkg, latest.Original()); err == nil {
+		health.Inactive = slices.Contains(release.Classifiers, inactiveClassifier)
+		description := release.Description
+		if len(description) > descriptionHead {
+			description = description[:descriptionHead]
+		}
+		health.Deprecated = deprecatedSummary.MatchString(release.Summary) || deprecatedDescription.MatchString(description)
+	}
+	if len(installed.Release) > 0 && len(latest.Release) > 0 && installed.Epoch == latest.Epoch {
+		health.MajorsBehind = max(latest.Release[0]-installed.Release[0], 0)
+	}
+
+	if health.LastRelease != nil && config.now().Sub(*health.LastRelease) > config.staleAfter() {
+		health.Signals = append(health.Signals, fmt.Sprintf("no release since %s", health.LastRelease.Format("2006-01-02")))
+	}
+	if health.Releases == 1 {
+		health.Signals = append(health.Signals, "only one release")
+	}
+	if health.Inactive {
+		health.Signals = append(health.Signals, "marked inactive")
+	}
+	if health.Deprecated {
+		health.Signals = append(health.Signals, "described as deprecated")
+	}
+	if health.MajorsBehind > config.maxMajorsBehind() {
+		health.Signals = append(health.Signals, fmt.Sprintf("%d major versions behind %s", health.MajorsBehind, health.LatestVersion))
+	}
+	if len(health.Signals) > 0 {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityWarning, utils.CodeProjectHealth,
+			"Package '%s' may be a risk: %s.", pkg.Name, strings.Join(health.Signals, ", ")))
+	}
+	return health
+}
+
+// HealthReportRow is the health of one package.
+type HealthReportRow struct {
+	Package string `json:"package"`
+	utils.Position
+	utils.Health
+}
+
+// BuildHealthReport collects what VerifyPackages found for each
+// package, in the order of files.
+func BuildHealthReport(packages []utils.Package, files utils.FileOrder) []HealthReportRow {
+	report := []HealthReportRow{}
+	for _, pkg := range packages {
+		if pkg.Health != nil {
+			report = append(report, HealthReportRow{Package: pkg.Name, Position: pkg.Position, Health: *pkg.Health})
+		}
+	}
+	utils.SortByPosition(report, files, func(row HealthReportRow) utils.Position { return row.Position })
+	return report
+}
@@ -0,0 +1,139 @@
+package input
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+func TestCheckHealth(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	release := func(version string, uploaded time.Time, summary string, classifiers ...string) *index.ProjectRelease {
+		return &index.ProjectRelease{
+			Release: index.Release{Version: version, Summary: summary, Classifiers: classifiers},
+			Files:   []index.File{{Filename: "pkg-" + version + ".tar.gz", UploadTime: uploaded}},
+		}
+	}
+	mem := index.Memory{
+		"healthy": {Name: "healthy", Releases: map[string]*index.ProjectRelease{
+			"1.0": release("1.0", now.AddDate(-1, 0, 0), "A library"),
+			"1.1": release("1.1", now.AddDate(0, -1, 0), "A library"),
+		}},
+		"abandoned": {Name: "abandoned", Releases: map[string]*index.ProjectRelease{
+			"0.1": release("0.1", now.AddDate(-5, 0, 0), "Deprecated, use something else", inactiveClassifier),
+		}},
+		"behind": {Name: "behind", Releases: map[string]*index.ProjectRelease{
+			"1.0":   release("1.0", now.AddDate(-3, 0, 0), ""),
+			"5.0":   release("5.0", now.AddDate(0, -1, 0), ""),
+			"6.0a1": release("6.0a1", now, ""),
+		}},
+	}
+
+	tests := []struct {
+		line    string
+		signals []string
+		latest  string
+	}{
+		{"healthy", []string{}, "1.1"},
+		{"abandoned", []string{"no release since 2021-01-01", "only one release", "marked inactive", "described as deprecated"}, "0.1"},
+		// pre-releases aren't the latest
+		{"behind==1.0", []string{"4 major versions behind 5.0"}, "5.0"},
+		{"behind==4.0", nil, ""},
+	}
+	for _, test := range tests {
+		reqFile, _ := ParseFile("requirements.txt", []byte(test.line+"\n"), nil, nil)
+		pkg := reqFile.Requirements[0]
+		lookup := newVersionLookup(VerifyConfig{Index: mem}, reqFile.Options)
+		diags := []utils.Diagnostic{}
+		health := checkHealth(context.Background(), pkg, reqFile.Options, lookup, &HealthConfig{Now: now}, &diags)
+
+		if test.signals == nil {
+			if health != nil {
+				t.Errorf("%s: got %+v, want nil for a version that isn't there", test.line, health)
+			}
+			continue
+		}
+		if health == nil {
+			t.Fatalf("%s: got nil health", test.line)
+		}
+		if !slices.Equal(health.Signals, test.signals) || health.LatestVersion != test.latest {
+			t.Errorf("%s: got %q latest %s, want %q latest %s", test.line, health.Signals, health.LatestVersion, test.signals, test.latest)
+		}
+		wantDiags := 0
+		if len(test.signals) > 0 {
+			wantDiags = 1
+		}
+		if len(diags) != wantDiags || (wantDiags == 1 && diags[0].Code != utils.CodeProjectHealth) {
+			t.Errorf("%s: got %v, want %d RQ028", test.line, diags, wantDiags)
+		}
+	}
+}
+
+func TestCheckHealthLastRelease(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	backport := now.AddDate(0, -1, 0)
+	release := func(uploaded time.Time) *index.ProjectRelease {
+		return &index.ProjectRelease{Files: []index.File{{Filename: "pkg.tar.gz", UploadTime: uploaded}}}
+	}
+	mem := index.Memory{
+		// the latest is old, but an older series still gets fixes
+		"backported": {Name: "backported", Releases: map[string]*index.ProjectRelease{
+			"1.9.0": release(now.AddDate(-6, 0, 0)),
+			"1.9.1": release(backport),
+			"2.0":   release(now.AddDate(-5, 0, 0)),
+		}},
+		"undated": {Name: "undated", Releases: map[string]*index.ProjectRelease{
+			"1.0": {}, "2.0": {},
+		}},
+	}
+
+	for _, test := range []struct {
+		name string
+		want *time.Time
+	}{
+		{"backported", &backport},
+		{"undated", nil},
+	} {
+		reqFile, _ := ParseFile("requirements.txt", []byte(test.name+"\n"), nil, nil)
+		lookup := newVersionLookup(VerifyConfig{Index: mem}, reqFile.Options)
+		diags := []utils.Diagnostic{}
+		health := checkHealth(context.Background(), reqFile.Requirements[0], reqFile.Options, lookup, &HealthConfig{Now: now}, &diags)
+
+		if (health.LastRelease == nil) != (test.want == nil) || (test.want != nil && !health.LastRelease.Equal(*test.want)) {
+			t.Errorf("%s: last release = %v, want %v", test.name, health.LastRelease, test.want)
+		}
+		if len(health.Signals) != 0 {
+			t.Errorf("%s: signals = %q, want none", test.name, health.Signals)
+		}
+		encoded, _ := json.Marshal(health)
+		if test.want == nil && strings.Contains(string(encoded), "last_release") {
+			t.Errorf("%s: %s has a last_release without a date", test.name, encoded)
+		}
+	}
+}
+
+func TestBuildHealthReport(t *testing.T) {
+	pkg := func(name, file string, line int, health *utils.Health) utils.Package {
+		return utils.Package{Name: name, Position: utils.Position{File: file, Line: line}, Health: health}
+	}
+	packages := []utils.Package{
+		pkg("b", "requirements.txt", 2, &utils.Health{Version: "1.0"}),
+		pkg("skipped", "requirements.txt", 3, nil),
+		pkg("c", "base.txt", 1, &utils.Health{Version: "2.0"}),
+		pkg("a", "requirements.txt", 1, &utils.Health{Version: "3.0"}),
+	}
+	report := BuildHealthReport(packages, utils.FileOrder{"requirements.txt", "base.txt"})
+	names := []string{}
+	for _, row := range report {
+		names = append(names, row.Package)
+	}
+	if want := []string{"a", "b", "c"}; !slices.Equal(names, want) {
+		t.Errorf("BuildHealthReport() order = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,46 @@
+package input
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IncludeResolver hands back the contents of files referenced with
+// `-r` and `-c`. Names are slash separated and already joined onto the
+// directory of the file that included them.
+type IncludeResolver interface {
+	ReadFile(name string) ([]byte, error)
+}
+
+// DirResolver reads includes from a directory on disk. Paths can't
+// climb out of the directory, `../../etc/passwd` stays inside of it.
+type DirResolver string
+
+func (root DirResolver) ReadFile(name string) ([]byte, error) {
+	clean := filepath.FromSlash(path.Clean("/" + name))
+	return os.ReadFile(filepath.Join(string(root), clean))
+}
+
+// MapResolver serves includes from files uploaded along with the main
+// requirements file, keyed by their name.
+type MapResolver map[string][]byte
+
+func (files MapResolver) ReadFile(name string) ([]byte, error) {
+	content, ok := files[path.Clean(name)]
+	if !ok {
+		return nil, fmt.Errorf("file '%s' was not provided", name)
+	}
+	return content, nil
+}
+
+// includePath resolves the target relative to the including file like
+// pip does, leaving urls alone.
+func includePath(from, target string) string {
+	if strings.Contains(target, "://") || path.IsAbs(target) {
+		return target
+	}
+	return path.Join(path.Dir(from), target)
+}
@@ -0,0 +1,122 @@
+package input
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+func TestParseFileIncludes(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		requirements []string
+		constraints  []string
+		fileOrder    []string
+		codes        []utils.Code
+		// where each diagnostic is, as file:line
+		at []string
+	}{
+		{
+			name: "nested",
+			files: map[string]string{
+				"requirements.txt":          "flask\n-r sub/base.txt\n",
+				"sub/base.txt":              "-r ../common.txt\n-c constraints.txt\nnumpy\n",
+				"sub/constraints.txt":       "urllib3<2\n-r nested-constraint.txt\n",
+				"sub/nested-constraint.txt": "idna<4\n",
+				"common.txt":                "requests\n",
+			},
+			requirements: []string{"flask", "requests", "numpy"},
+			constraints:  []string{"urllib3", "idna"},
+			fileOrder:    []string{"requirements.txt", "sub/base.txt", "common.txt", "sub/constraints.txt", "sub/nested-constraint.txt"},
+		},
+		{
+			name: "same file twice",
+			files: map[string]string{
+				"requirements.txt": "-r base.txt\n-r ./base.txt\n",
+				"base.txt":         "flask\n",
+			},
+			requirements: []string{"flask"},
+			fileOrder:    []string{"requirements.txt", "base.txt"},
+		},
+		{
+			name: "self include",
+			files: map[string]string{
+				"requirements.txt": "flask\n-r requirements.txt\n",
+			},
+			requirements: []string{"flask"},
+			fileOrder:    []string{"requirements.txt"},
+			codes:        []utils.Code{utils.CodeIncludeCycle},
+			at:           []string{"requirements.txt:2"},
+		},
+		{
+			name: "cycle through another file",
+			files: map[string]string{
+				"requirements.txt": "-r base.txt\n",
+				"base.txt":         "flask\n-r requirements.txt\n",
+			},
+			requirements: []string{"flask"},
+			fileOrder:    []string{"requirements.txt", "base.txt"},
+			codes:        []utils.Code{utils.CodeIncludeCycle},
+			at:           []string{"base.txt:2"},
+		},
+		{
+			name: "missing include",
+			files: map[string]string{
+				"requirements.txt": "flask\n\n-r missing.txt\n-c ../../outside.txt\n",
+			},
+			requirements: []string{"flask"},
+			fileOrder:    []string{"requirements.txt"},
+			codes:        []utils.Code{utils.CodeIncludeFailed, utils.CodeIncludeFailed},
+			at:           []string{"requirements.txt:3", "requirements.txt:4"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range test.files {
+				path := filepath.Join(root, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			reqFile, diags := ParseFile("requirements.txt", []byte(test.files["requirements.txt"]), DirResolver(root), nil)
+
+			names := func(pkgs []utils.Package) []string {
+				result := []string{}
+				for _, pkg := range pkgs {
+					result = append(result, pkg.Name)
+				}
+				return result
+			}
+			if got := names(reqFile.Requirements); !slices.Equal(got, test.requirements) {
+				t.Errorf("requirements = %v, want %v", got, test.requirements)
+			}
+			if got := names(reqFile.Constraints); !slices.Equal(got, test.constraints) {
+				t.Errorf("constraints = %v, want %v", got, test.constraints)
+			}
+			if !slices.Equal(reqFile.Files, test.fileOrder) {
+				t.Errorf("files = %v, want %v", reqFile.Files, test.fileOrder)
+			}
+			if len(diags) != len(test.codes) {
+				t.Fatalf("diagnostics = %v, want codes %v", diags, test.codes)
+			}
+			for i, diag := range diags {
+				if diag.Code != test.codes[i] {
+					t.Errorf("diagnostic %d = %v, want %v", i, diag, test.codes[i])
+				}
+				if at := fmt.Sprintf("%s:%d", diag.File, diag.Line); at != test.at[i] {
+					t.Errorf("diagnostic %d at %s, want %s", i, at, test.at[i])
+				}
+			}
+		})
+	}
+}
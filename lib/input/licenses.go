@@ -0,0 +1,79 @@
+package input
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/license"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+// checkLicense reads the license of the version pip would install out
+// of its metadata and checks it against the policy. Packages it can't
+// find one for are warned about, or rejected with DenyUnknown.
+func checkLicense(ctx context.Context, pkg utils.Package, opts GlobalOptions, lookup *versionLookup, policy *license.Policy, diags *[]utils.Diagnostic) (*utils.LicenseInfo, bool) {
+	info := &utils.LicenseInfo{}
+	if _, installed, ok := installCandidate(ctx, pkg, opts, lookup); ok {
+		info.Version = installed.Original()
+		release, err := lookup.release(ctx, pkg, info.Version)
+		if err != nil {
+			*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityWarning, utils.CodeIndexError,
+				"An error occurred while retrieving the metadata of '%s==%s': %v", pkg.Name, info.Version, err))
+		} else {
+			info.Expression, info.Source = license.Normalize(release.LicenseExpression, release.License, release.Classifiers)
+			if info.Expression == "" && !strings.Contains(release.License, "\n") {
+				info.Raw = strings.TrimSpace(release.License)
+			}
+		}
+	}
+
+	if info.Expression == "" {
+		severity := utils.SeverityWarning
+		if policy.DenyUnknown {
+			severity = utils.SeverityError
+		}
+		msg := "Could not work out the license of '" + pkg.Name + "'"
+		if info.Raw != "" {
+			msg += ", its metadata says '" + info.Raw + "'"
+		}
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, severity, utils.CodeUnknownLicense, "%s.", msg))
+		return info, !policy.DenyUnknown
+	}
+
+	blocked, err := policy.Check(info.Expression)
+	if err != nil {
+		// Normalize only hands back expressions that parse
+		return info, true
+	}
+	if len(blocked) > 0 {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeLicenseViolation,
+			"Package '%s' is licensed under '%s', the license policy doesn't allow %s.", pkg.Name, info.Expression, strings.Join(blocked, ", ")))
+		return info, false
+	}
+	return info, true
+}
+
+// unknownLicense is the bucket for packages without a usable license.
+const unknownLicense = "unknown"
+
+// BuildLicenseReport groups the packages VerifyPackages checked by
+// license expression, with an "unknown" bucket for the ones it
+// couldn't work out.
+func BuildLicenseReport(packages []utils.Package) map[string][]string {
+	report := map[string][]string{}
+	for _, pkg := range packages {
+		if pkg.License == nil {
+			continue
+		}
+		expression := pkg.License.Expression
+		if expression == "" {
+			expression = unknownLicense
+		}
+		report[expression] = append(report[expression], pkg.Name)
+	}
+	for _, names := range report {
+		sort.Strings(names)
+	}
+	return report
+}
@@ -0,0 +1,266 @@
+package input
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+)
+
+const DefaultIndexURL = "https://pypi.org/simple"
+
+// GlobalOptions are the pip options that apply to the whole file no
+// matter which line they show up on. Options from included files are
+// merged in too, same as pip.
+type GlobalOptions struct {
+	IndexURL       string
+	ExtraIndexURLs []string
+	NoIndex        bool
+	FindLinks      []string
+	// normalized names, or :all:, with pip's rules for the two options
+	// overriding each other already applied, see allowedFormats
+	NoBinary      []string
+	OnlyBinary    []string
+	Pre           bool
+	TrustedHosts  []string
+	RequireHashes bool
+	PreferBinary  bool
+}
+
+// IndexURLs gives every index to look packages up on, the main one
+// first.
+func (opts GlobalOptions) IndexURLs() []string {
+	if opts.NoIndex {
+		return nil
+	}
+	indexURL := opts.IndexURL
+	if indexURL == "" {
+		indexURL = DefaultIndexURL
+	}
+	return append([]string{indexURL}, opts.ExtraIndexURLs...)
+}
+
+type option struct {
+	Name  string
+	Value string
+}
+
+// short flags pip accepts and the long option they stand for
+var shortOptions = map[string]string{
+	"-i": "--index-url",
+	"-f": "--find-links",
+	"-r": "--requirement",
+	"-c": "--constraint",
+	"-e": "--editable",
+}
+
+// options that don't take a value
+var flagOptions = map[string]bool{
+	"--pre":            true,
+	"--no-index":       true,
+	"--require-hashes": true,
+	"--prefer-binary":  true,
+}
+
+// global options that take a value
+var valueOptions = map[string]bool{
+	"--index-url":       true,
+	"--extra-index-url": true,
+	"--find-links":      true,
+	"--no-binary":       true,
+	"--only-binary":     true,
+	"--trusted-host":    true,
+	"--use-feature":     true,
+	"--requirement":     true,
+	"--constraint":      true,
+	"--editable":        true,
+}
+
+// options allowed after a requirement on the same line
+var requirementOptions = map[string]bool{
+	"--hash":            true,
+	"--config-settings": true,
+	"--global-option":   true,
+	"--install-option":  true,
+}
+
+// parseOptions reads option tokens like `--index-url=URL`,
+// `--index-url URL`, `-i URL` and `-iURL`.
+func parseOptions(tokens []string) ([]option, error) {
+	opts := []option{}
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		var name, value string
+		hasValue := false
+
+		switch {
+		case strings.HasPrefix(token, "--"):
+			name, value, hasValue = strings.Cut(token, "=")
+		case strings.HasPrefix(token, "-") && len(token) >= 2:
+			long, ok := shortOptions[token[:2]]
+			if !ok {
+				return nil, fmt.Errorf("unknown option '%s'", token)
+			}
+			name = long
+			if len(token) > 2 {
+				value, hasValue = token[2:], true
+			}
+		default:
+			return nil, fmt.Errorf("unexpected '%s', expected an option", token)
+		}
+
+		if !flagOptions[name] && !valueOptions[name] && !requirementOptions[name] {
+			return nil, fmt.Errorf("unsupported option '%s'", name)
+		}
+		if flagOptions[name] {
+			if hasValue {
+				return nil, fmt.Errorf("option '%s' does not take a value", name)
+			}
+			opts = append(opts, option{Name: name})
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(tokens) {
+				return nil, fmt.Errorf("option '%s' needs a value", name)
+			}
+			i++
+			value = tokens[i]
+		}
+		opts = append(opts, option{Name: name, Value: value})
+	}
+	return opts, nil
+}
+
+// splitRequirementOptions splits `foo==1.0 --hash=sha256:...` into the
+// requirement and its trailing options.
+func splitRequirementOptions(line string) (string, []option, error) {
+	fields := strings.Fields(line)
+	starts := fieldStarts(line)
+	for i, field := range fields {
+		if i > 0 && strings.HasPrefix(field, "--") {
+			idx := starts[i]
+			opts, err := parseOptions(fields[i:])
+			if err != nil {
+				return "", nil, err
+			}
+			for _, opt := range opts {
+				if !requirementOptions[opt.Name] {
+					return "", nil, fmt.Errorf("option '%s' is not allowed on a requirement line", opt.Name)
+				}
+			}
+			return strings.TrimSpace(line[:idx]), opts, nil
+		}
+	}
+	return line, nil, nil
+}
+
+// fieldStarts gives the byte offset of each of the fields
+// strings.Fields splits line into, by the same whitespace rule.
+func fieldStarts(line string) []int {
+	starts := []int{}
+	inField := false
+	for i, r := range line {
+		if unicode.IsSpace(r) {
+			inField = false
+		} else if !inField {
+			starts = append(starts, i)
+			inField = true
+		}
+	}
+	return starts
+}
+
+// apply records a global option. ok is false if the option isn't a
+// global one.
+func (opts *GlobalOptions) apply(opt option) bool {
+	switch opt.Name {
+	case "--index-url":
+		opts.IndexURL = opt.Value
+	case "--extra-index-url":
+		opts.ExtraIndexURLs = append(opts.ExtraIndexURLs, opt.Value)
+	case "--no-index":
+		opts.NoIndex = true
+	case "--find-links":
+		opts.FindLinks = append(opts.FindLinks, opt.Value)
+	case "--no-binary":
+		opts.NoBinary, opts.OnlyBinary = formatControl(opts.NoBinary, opts.OnlyBinary, opt.Value)
+	case "--only-binary":
+		opts.OnlyBinary, opts.NoBinary = formatControl(opts.OnlyBinary, opts.NoBinary, opt.Value)
+	case "--pre":
+		opts.Pre = true
+	case "--trusted-host":
+		opts.TrustedHosts = append(opts.TrustedHosts, opt.Value)
+	case "--require-hashes":
+		opts.RequireHashes = true
+	case "--prefer-binary":
+		opts.PreferBinary = true
+	case "--use-feature":
+		// only turns on pip's experimental behaviour, nothing checked
+		// here depends on it
+	default:
+		return false
+	}
+	return true
+}
+
+// formatControl adds the value of a --no-binary or --only-binary option
+// to its list, target, the same way pip's FormatControl does. It takes
+// comma separated names, :all:, which empties the other list and
+// ignores the names after it unless a :none: follows, or :none:, which
+// empties the list. A name on one list comes off the other one.
+func formatControl(target, other []string, value string) ([]string, []string) {
+	names := strings.Split(value, ",")
+	for {
+		i := slices.Index(names, ":all:")
+		if i < 0 {
+			break
+		}
+		target, other = []string{":all:"}, nil
+		names = names[i+1:]
+		if !slices.Contains(names, ":none:") {
+			return target, other
+		}
+	}
+	for _, name := range names {
+		switch name = strings.TrimSpace(name); name {
+		case "":
+		case ":none:":
+			target = nil
+		default:
+			name = pep508.NormalizeName(name)
+			other = slices.DeleteFunc(other, func(n string) bool { return n == name })
+			if !slices.Contains(target, name) {
+				target = append(target, name)
+			}
+		}
+	}
+	return target, other
+}
+
+// allowedFormats says whether pip may install the package from a wheel
+// and from an sdist. A name beats :all:, and --only-binary wins when a
+// name ends up on both.
+func (opts GlobalOptions) allowedFormats(name string) (binary, source bool) {
+	name = pep508.NormalizeName(name)
+	switch {
+	case slices.Contains(opts.OnlyBinary, name):
+		return true, false
+	case slices.Contains(opts.NoBinary, name):
+		return false, true
+	case slices.Contains(opts.OnlyBinary, ":all:"):
+		return true, false
+	case slices.Contains(opts.NoBinary, ":all:"):
+		return false, true
+	}
+	return true, true
+}
+
+// indexConfig is config.IndexConfig with the file's --trusted-host
+// options added, for the index package to apply where it's safe.
+func (opts GlobalOptions) indexConfig(config index.Config) index.Config {
+	config.FileTrustedHosts = append(slices.Clone(config.FileTrustedHosts), opts.TrustedHosts...)
+	return config
+}
@@ -0,0 +1,140 @@
+package input
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+func TestAllowedFormats(t *testing.T) {
+	tests := []struct {
+		lines          []string
+		name           string
+		binary, source bool
+	}{
+		{nil, "numpy", true, true},
+		{[]string{"--no-binary :all:"}, "numpy", false, true},
+		{[]string{"--only-binary :all:"}, "numpy", true, false},
+		{[]string{"--no-binary NumPy"}, "numpy", false, true},
+		{[]string{"--no-binary numpy"}, "scipy", true, true},
+		// a name beats :all:
+		{[]string{"--only-binary :all:", "--no-binary numpy"}, "numpy", false, true},
+		{[]string{"--only-binary :all:", "--no-binary numpy"}, "scipy", true, false},
+		// the later option takes the name off the other list
+		{[]string{"--no-binary numpy", "--only-binary numpy"}, "numpy", true, false},
+		// :all: empties the other list, and ignores names after it
+		{[]string{"--no-binary numpy", "--only-binary :all:,numpy"}, "numpy", true, false},
+		{[]string{"--no-binary :all:", "--no-binary :none:"}, "numpy", true, true},
+		{[]string{"--no-binary :all:,:none:,numpy"}, "scipy", true, true},
+		{[]string{"--no-binary :all:,:none:,numpy"}, "numpy", false, true},
+	}
+	for _, test := range tests {
+		reqFile, diags := ParseFile("requirements.txt", []byte(strings.Join(test.lines, "\n")), nil, nil)
+		if len(diags) > 0 {
+			t.Fatalf("ParseFile(%v): %v", test.lines, diags)
+		}
+		binary, source := reqFile.Options.allowedFormats(test.name)
+		if binary != test.binary || source != test.source {
+			t.Errorf("%v: allowedFormats(%q) = %v, %v, want %v, %v", test.lines, test.name, binary, source, test.binary, test.source)
+		}
+	}
+}
+
+func TestFindLinksIsReported(t *testing.T) {
+	reqFile, diags := ParseFile("requirements.txt", []byte("--find-links ./wheels\n"), nil, nil)
+	if len(reqFile.Options.FindLinks) != 1 || len(diags) != 1 || diags[0].Code != utils.CodeUnverifiable {
+		t.Errorf("ParseFile() = %+v, %v, want the location kept with an RQ008 note", reqFile.Options, diags)
+	}
+}
+
+func TestUseFeatureIsAccepted(t *testing.T) {
+	content := "--use-feature=truststore\n--use-feature fast-deps\nrequests\n"
+	reqFile, diags := ParseFile("requirements.txt", []byte(content), nil, nil)
+	if len(diags) > 0 || len(reqFile.Requirements) != 1 {
+		t.Errorf("ParseFile() = %v, %v, want the option accepted", reqFile.Requirements, diags)
+	}
+	// it's still a global option
+	if _, diags := ParseFile("requirements.txt", []byte("requests --use-feature=truststore\n"), nil, nil); len(diags) != 1 || diags[0].Code != utils.CodeInvalidOption {
+		t.Errorf("ParseFile() = %v, want an RQ007 on the requirement line", diags)
+	}
+}
+
+func TestCheckWheelsFormats(t *testing.T) {
+	files := func(names ...string) []index.File {
+		result := []index.File{}
+		for _, name := range names {
+			result = append(result, index.File{Filename: name})
+		}
+		return result
+	}
+	mem := index.Memory{
+		"both": {Name: "both", Releases: map[string]*index.ProjectRelease{
+			"1.0": {Files: files("both-1.0-py3-none-any.whl", "both-1.0.tar.gz")},
+		}},
+		"wheelonly": {Name: "wheelonly", Releases: map[string]*index.ProjectRelease{
+			"1.0": {Files: files("wheelonly-1.0-py3-none-any.whl")},
+		}},
+		"sdistonly": {Name: "sdistonly", Releases: map[string]*index.ProjectRelease{
+			"1.0": {Files: files("sdistonly-1.0.tar.gz")},
+		}},
+		// the newest release hasn't got a wheel yet
+		"lagging": {Name: "lagging", Releases: map[string]*index.ProjectRelease{
+			"1.0": {Files: files("lagging-1.0-py3-none-any.whl")},
+			"2.0": {Files: files("lagging-2.0.tar.gz")},
+		}},
+	}
+
+	tests := []struct {
+		options string
+		name    string
+		code    utils.Code
+		ok      bool
+		// the version in the message
+		version string
+	}{
+		{"", "both", utils.Code{}, true, ""},
+		{"", "sdistonly", utils.CodeBuildFromSource, true, "1.0"},
+		{"--no-binary both", "both", utils.CodeBuildFromSource, true, "1.0"},
+		{"--no-binary :all:", "wheelonly", utils.CodeNoCompatibleDistribution, false, "1.0"},
+		{"--only-binary :all:", "sdistonly", utils.CodeNoCompatibleDistribution, false, "1.0"},
+		{"--only-binary :all:", "both", utils.Code{}, true, ""},
+		{"", "lagging", utils.CodeBuildFromSource, true, "2.0"},
+		{"--prefer-binary", "lagging", utils.Code{}, true, ""},
+	}
+	for _, test := range tests {
+		reqFile, _ := ParseFile("requirements.txt", []byte(test.options+"\n"+test.name+"\n"), nil, nil)
+		pkg := reqFile.Requirements[0]
+		pkg.Targets = []string{DefaultTarget}
+		lookup := newVersionLookup(VerifyConfig{Index: mem}, reqFile.Options)
+		diags := []utils.Diagnostic{}
+		_, ok := checkWheels(context.Background(), pkg, reqFile.Options, lookup, []Target{TargetProfiles[DefaultTarget]}, &diags)
+
+		if ok != test.ok {
+			t.Errorf("%q %s: ok = %v, want %v", test.options, test.name, ok, test.ok)
+		}
+		switch {
+		case test.code == utils.Code{} && len(diags) > 0:
+			t.Errorf("%q %s: got %v, want no diagnostics", test.options, test.name, diags)
+		case test.code != utils.Code{} && (len(diags) != 1 || diags[0].Code != test.code || !strings.Contains(diags[0].Message, "=="+test.version)):
+			t.Errorf("%q %s: got %v, want one %s for %s", test.options, test.name, diags, test.code, test.version)
+		}
+	}
+}
+
+func TestSplitRequirementOptions(t *testing.T) {
+	// any whitespace strings.Fields splits on can come before an option
+	for _, sep := range []string{" ", "\t", "\v", "\f", "\u00a0", " \t "} {
+		line := "foo==1.0" + sep + "--hash=sha256:abc"
+		req, opts, err := splitRequirementOptions(line)
+		if err != nil || req != "foo==1.0" || len(opts) != 1 || opts[0].Value != "sha256:abc" {
+			t.Errorf("splitRequirementOptions(%q) = %q, %v, %v", line, req, opts, err)
+		}
+		reqFile, diags := ParseFile("requirements.txt", []byte(line+"\n"), nil, nil)
+		if len(diags) > 0 || len(reqFile.Requirements) != 1 || len(reqFile.Requirements[0].Hashes) != 1 {
+			t.Errorf("ParseFile(%q) = %v, %v, want foo with its hash", line, reqFile.Requirements, diags)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package input
+
+import (
+	"context"
+
+	"github.com/DerekCorniello/pip-req-valid/pep440"
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+	"github.com/DerekCorniello/pip-req-valid/resolve"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+// Upgrade is another version of a package and how big a jump it is.
+type Upgrade struct {
+	Version string `json:"version"`
+	// major, minor or patch, empty when it's the current version or
+	// older
+	Behind string `json:"behind,omitempty"`
+	// the version is older than the current one
+	Downgrade bool `json:"downgrade,omitempty"`
+}
+
+// OutdatedPackage is how far a package in the file is behind.
+type OutdatedPackage struct {
+	Package string `json:"package"`
+	utils.Position
+	// the version pip installs, the newest the specifiers allow
+	Current string `json:"current"`
+	// the newest stable release
+	Latest Upgrade `json:"latest"`
+	// the newest release that still resolves on every target with the
+	// whole file upgraded, nil when that doesn't resolve. It can be a
+	// downgrade when another package holds this one back.
+	LatestCompatible *Upgrade `json:"latest_compatible,omitempty"`
+}
+
+// CheckOutdated verifies the file, then works out how far behind each
+// package that checks out is. Unlike verification it walks every
+// version instead of stopping at the first match, and it resolves the
+// file once per target with every package's specifiers dropped to find
+// the newest versions that still go together.
+func CheckOutdated(ctx context.Context, reqFile *RequirementsFile, config VerifyConfig) ([]OutdatedPackage, []utils.Diagnostic) {
+	opts := reqFile.Options
+	lookup := newVersionLookup(config, opts)
+	verified, _, diags := verifyPackages(ctx, reqFile.Requirements, opts, config, lookup)
+	resolver := &resolve.Resolver{Index: lookup.index, Pre: opts.Pre}
+	for _, pkg := range reqFile.Constraints {
+		if pkg.Requirement != nil {
+			resolver.Constraints = append(resolver.Constraints, resolve.Root{Requirement: pkg.Requirement, Source: pkg.File})
+		}
+	}
+	compatible := latestCompatible(ctx, resolver, reqFile, config.targets())
+
+	report := []OutdatedPackage{}
+	for _, pkg := range verified {
+		versions, installed, ok := installCandidate(ctx, pkg, opts, lookup)
+		if !ok {
+			continue
+		}
+		stable := []*pep440.Version{}
+		for _, v := range parseVersions(versions, false) {
+			if !v.IsPrerelease() || opts.Pre {
+				stable = append(stable, v)
+			}
+		}
+		latest := newest(stable)
+		if latest == nil || latest.LessThan(installed) {
+			latest = installed
+		}
+		row := OutdatedPackage{
+			Package:  pkg.Name,
+			Position: pkg.Position,
+			Current:  installed.Original(),
+			Latest:   upgradeTo(installed, latest),
+		}
+		if v, ok := compatible[pep508.NormalizeName(pkg.Name)]; ok {
+			upgrade := upgradeTo(installed, v)
+			row.LatestCompatible = &upgrade
+		}
+		report = append(report, row)
+	}
+	return report, diags
+}
+
+// latestCompatible resolves the file with every package's specifiers
+// left out, once per target, and gives the lowest version picked for
+// each package across the targets. It's nil when a target doesn't
+// resolve.
+func latestCompatible(ctx context.Context, resolver *resolve.Resolver, reqFile *RequirementsFile, targets []Target) map[string]*pep440.Version {
+	roots := []resolve.Root{}
+	for _, pkg := range reqFile.Requirements {
+		if pkg.Requirement == nil {
+			continue
+		}
+		relaxed := *pkg.Requirement
+		relaxed.Specifiers = nil
+		roots = append(roots, resolve.Root{Requirement: &relaxed, Source: pkg.File})
+	}
+
+	lowest := map[string]*pep440.Version{}
+	for _, target := range targets {
+		resolution, err := resolver.Resolve(ctx, roots, target.Env)
+		if err != nil {
+			return nil
+		}
+		for _, pin := range resolution.Pins {
+			if pin.Version == "" {
+				continue
+			}
+			v, err := pep440.Parse(pin.Version)
+			if err != nil {
+				continue
+			}
+			if low, ok := lowest[pin.Name]; !ok || v.LessThan(low) {
+				lowest[pin.Name] = v
+			}
+		}
+	}
+	return lowest
+}
+
+func upgradeTo(current, to *pep440.Version) Upgrade {
+	return Upgrade{Version: to.Original(), Behind: behind(current, to), Downgrade: to.LessThan(current)}
+}
+
+// behind classifies the jump from one version to a newer one by the
+// first release segment that changes, anything past the third counts
+// as a patch.
+func behind(from, to *pep440.Version) string {
+	if !from.LessThan(to) {
+		return ""
+	}
+	if from.Epoch != to.Epoch {
+		return "major"
+	}
+	for i, kind := range []string{"major", "minor"} {
+		if segment(from, i) != segment(to, i) {
+			return kind
+		}
+	}
+	return "patch"
+}
+
+func segment(v *pep440.Version, i int) int {
+	if i < len(v.Release) {
+		return v.Release[i]
+	}
+	return 0
+}
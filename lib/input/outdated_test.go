@@ -0,0 +1,85 @@
+package input
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	"github.com/DerekCorniello/pip-req-valid/pep440"
+)
+
+func TestCheckOutdated(t *testing.T) {
+	project := func(name string, releases map[string][]string) *index.Project {
+		p := &index.Project{Name: name, Releases: map[string]*index.ProjectRelease{}}
+		for version, requires := range releases {
+			p.Releases[version] = &index.ProjectRelease{
+				Release: index.Release{Name: name, Version: version, RequiresDist: requires},
+				Files:   []index.File{{Filename: name + "-" + version + "-py3-none-any.whl"}},
+			}
+		}
+		return p
+	}
+	mem := index.Memory{
+		"app": project("app", map[string][]string{"1.0.0": nil, "1.2.0": nil, "2.0.0": {"lib>=2"}, "3.0.0b1": nil}),
+		"lib": project("lib", map[string][]string{"1.0.0": nil, "1.1.0": nil, "2.0.0": nil}),
+		// holds lib below 2, so app can't go past 1.2.0 with it
+		"tool": project("tool", map[string][]string{"1.0.0": {"lib<2"}}),
+	}
+	content := "app>=1.0\nlib==1.0.0\ntool\n"
+	reqFile, _ := ParseFile("requirements.txt", []byte(content), nil, nil)
+
+	report, diags := CheckOutdated(context.Background(), reqFile, VerifyConfig{Index: mem})
+	if len(diags) > 0 {
+		t.Fatalf("CheckOutdated() diagnostics: %v", diags)
+	}
+	want := []OutdatedPackage{
+		{
+			// >= installs the newest, so it's up to date
+			Package: "app", Current: "2.0.0",
+			Latest: Upgrade{"2.0.0", "", false},
+			// tool holds lib back, and lib==2 is what app 2.0.0 needs
+			LatestCompatible: &Upgrade{"1.2.0", "", true},
+		},
+		{
+			Package: "lib", Current: "1.0.0",
+			Latest:           Upgrade{"2.0.0", "major", false},
+			LatestCompatible: &Upgrade{"1.1.0", "minor", false},
+		},
+		{
+			Package: "tool", Current: "1.0.0",
+			Latest:           Upgrade{"1.0.0", "", false},
+			LatestCompatible: &Upgrade{"1.0.0", "", false},
+		},
+	}
+	if len(report) != len(want) {
+		t.Fatalf("CheckOutdated() = %+v, want %d packages", report, len(want))
+	}
+	for i, row := range report {
+		w := want[i]
+		if row.Package != w.Package || row.Current != w.Current || row.Latest != w.Latest ||
+			row.LatestCompatible == nil || *row.LatestCompatible != *w.LatestCompatible {
+			t.Errorf("row %d = %+v (compatible %+v), want %+v (compatible %+v)", i, row, row.LatestCompatible, w, w.LatestCompatible)
+		}
+	}
+}
+
+func TestBehind(t *testing.T) {
+	tests := []struct {
+		from, to, want string
+	}{
+		{"1.0", "1.0", ""},
+		{"2.0", "1.0", ""},
+		{"1.0", "2.0", "major"},
+		{"1.0", "1.1", "minor"},
+		{"1.0", "1.0.1", "patch"},
+		{"1.0.0.1", "1.0.0.2", "patch"},
+		{"1.0", "1!0.1", "major"},
+	}
+	for _, test := range tests {
+		from := pep440.MustParse(test.from)
+		to := pep440.MustParse(test.to)
+		if got := behind(from, to); got != test.want {
+			t.Errorf("behind(%s, %s) = %q, want %q", test.from, test.to, got, test.want)
+		}
+	}
+}
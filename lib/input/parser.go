@@ -1,48 +1,50 @@
 package input
 
 import (
-	"bufio"
-	"bytes"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
-	"sync"
 
+	"github.com/DerekCorniello/pip-req-valid/pep508"
 	utils "github.com/DerekCorniello/pip-req-valid/utils"
 )
 
-func parseLine(line string, details *[]string, wg *sync.WaitGroup) (utils.Package, error) {
-
-	defer wg.Done()
+// parseLine parses one requirement, span gives where in the file a range
+// of bytes of line is.
+func parseLine(line string, pos utils.Position, span func(start, end int) utils.Position, diags *[]utils.Diagnostic) utils.Package {
 
+	// comments were already stripped by preprocess
 	line = strings.TrimSpace(line)
-	// handles comment and empty lines
-	if line == "" || strings.HasPrefix(line, "#") {
-		return utils.Package{}, nil
-	}
-
-	// comments can trail actual commands, split it here
-	if strings.Contains(line, "#") {
-		line = strings.Split(line, "#")[0]
+	if line == "" {
+		return utils.Package{}
 	}
 
-	// handles any of the reference or constraints tags, will
-	// print a message here to tell user to run the other file
-	// as well.
+	// includes are followed by ParseFile, any other option can't be
+	// checked so we just pass it along as local
 	if strings.HasPrefix(line, "-") {
-		*details = append(*details, fmt.Sprintf("Parsed an input with a tag reference to another file: %v. Please run the file through the tool following this run.\n", line))
-		return utils.Package{Name: line, VersionSpecs: []string{"local"}}, nil
+		*diags = append(*diags, utils.NewDiagnostic(pos, utils.SeverityInfo, utils.CodeUnverifiable,
+			"Cannot verify option line: %v", line))
+		return utils.Package{Name: line, VersionSpecs: []string{"local"}}
 	}
 
-	// handles external packages
-	if strings.Contains(line, "http") {
+	// handles bare external urls like `git+https://...`, `name @ url`
+	// direct references are left to the PEP 508 parser below
+	if isBareURL(line) {
+		// VCS and file urls are kept whole, verification reports them as
+		// something it can't check
+		scheme := line[:strings.Index(line, "://")]
+		if strings.Contains(scheme, "+") || strings.EqualFold(scheme, "file") {
+			return utils.Package{Name: line, VersionSpecs: []string{"latest", "url"}}
+		}
 		re := regexp.MustCompile(`http.*`)
 		matches := re.FindStringSubmatch(line)
 		if matches == nil {
-			return utils.Package{},
-				fmt.Errorf("Error in regex operation finding http url in '%v'", line)
+			*diags = append(*diags, utils.NewDiagnostic(pos, utils.SeverityError, utils.CodeInvalidRequirement,
+				"Could not find an http url in '%v'", line))
+			return utils.Package{}
 		}
-		return utils.Package{Name: matches[0], VersionSpecs: []string{"latest", "url"}}, nil
+		return utils.Package{Name: matches[0], VersionSpecs: []string{"latest", "url"}}
 	}
 
 	// handles local refs
@@ -51,91 +53,221 @@ func parseLine(line string, details *[]string, wg *sync.WaitGroup) (utils.Packag
 		strings.HasPrefix(line, "..") ||
 		strings.HasSuffix(line, ".whl") {
 
-		*details = append(*details, fmt.Sprintf("Cannot verify local file: %v\n", line))
-		return utils.Package{Name: line, VersionSpecs: []string{"local"}}, nil
+		*diags = append(*diags, utils.NewDiagnostic(pos, utils.SeverityInfo, utils.CodeUnverifiable,
+			"Cannot verify local file: %v", line))
+		return utils.Package{Name: line, VersionSpecs: []string{"local"}}
 	}
 
-	// regex to match name and optional extras [extras]
-	// this should handle all of the other __stuff__
-	re := regexp.MustCompile(`^([a-zA-Z0-9_\-]+)(\[[^\]]*\])?`)
-	matches := re.FindStringSubmatch(line)
-	if matches == nil {
-		return utils.Package{Name: "invalid"}, fmt.Errorf("invalid format: '%s'", line)
+	// everything else should be a PEP 508 requirement
+	req, err := pep508.Parse(line)
+	if err != nil {
+		errPos := pos
+		msg := err.Error()
+		// the diagnostic carries the column, no need to repeat it
+		if parseErr, ok := err.(*pep508.ParseError); ok {
+			errPos = span(parseErr.Pos, len(line))
+			msg = parseErr.Msg
+		}
+		*diags = append(*diags, utils.NewDiagnostic(errPos, utils.SeverityError, utils.CodeInvalidRequirement,
+			"An error occurred parsing package '%s': %s", line, msg))
+		return utils.Package{Name: "invalid"}
 	}
 
-	name := matches[1]
-	extras := strings.Trim(matches[2], "[]")
-	remaining := strings.TrimSpace(line[len(matches[0]):])
-	var versionSpecs []string
-	var envMarker string
-
-	// Check for environment marker (split on ;)
-	if strings.Contains(remaining, ";") {
-		parts := strings.SplitN(remaining, ";", 2)
-		remaining = strings.TrimSpace(parts[0])
-		envMarker = strings.TrimSpace(parts[1])
+	pkg := utils.NewPackage(req)
+	// works as is, pip normalizes it too, but the canonical spelling
+	// is easier to grep for and diff
+	if canonical := pkg.CanonicalName(); canonical != req.Name {
+		namePos := span(0, len(req.Name))
+		diag := utils.NewDiagnostic(namePos, utils.SeverityInfo, utils.CodeNonCanonicalName,
+			"Package name '%s' is not in canonical form.", req.Name)
+		diag.Fix = canonical
+		*diags = append(*diags, diag)
 	}
-
-	// Split version specifiers by commas
-	if remaining != "" {
-		versionSpecs = strings.Split(remaining, ",")
-		for i := range versionSpecs {
-			versionSpecs[i] = strings.TrimSpace(versionSpecs[i])
+	// specifiers come before any marker, so the first `==` is the pin's
+	if version, pinned := pinnedVersion(pkg); pinned {
+		if i := strings.Index(line, "=="); i >= 0 {
+			if j := strings.Index(line[i:], version); j >= 0 {
+				pkg.PinPosition = span(i+j, i+j+len(version))
+			}
 		}
 	}
+	return pkg
+
+}
 
-	return utils.Package{
-		Name:         name,
-		Extras:       extras,
-		VersionSpecs: versionSpecs,
-		EnvMarker:    envMarker,
-	}, nil
+// a bare url has a scheme right at the start, as opposed to a
+// `name @ url` direct reference that has the name and `@` first
+func isBareURL(line string) bool {
+	idx := strings.Index(line, "://")
+	if idx <= 0 {
+		return false
+	}
+	return !strings.ContainsAny(line[:idx], "@ \t")
+}
 
+// RequirementsFile is everything ParseFile pulled out of a file and
+// its includes.
+type RequirementsFile struct {
+	Requirements []utils.Package
+	// constraints only limit versions, they don't ask for anything to
+	// be installed
+	Constraints []utils.Package
+	Options     GlobalOptions
+	// every file read, in the order they were parsed in
+	Files utils.FileOrder
 }
 
-func ParseFile(fileContent []byte) ([]utils.Package, []error) {
+// fileParser carries the state of one ParseFile call down through
+// all of the included files.
+type fileParser struct {
+	resolver IncludeResolver
+	env      map[string]string
+	// the chain of files being parsed right now, used to catch cycles
+	stack []string
+	seen  map[string]bool
+	// the first requirement on each package, by canonical name and
+	// marker, requirements under different markers are for different
+	// environments
+	required map[string]utils.Package
+	result   RequirementsFile
+	diags    []utils.Diagnostic
+}
 
-	reader := bytes.NewReader(fileContent)
-	scanner := bufio.NewScanner(reader)
-	scanner.Split(bufio.ScanLines)
+// ParseFile parses a requirements file along with anything it pulls in
+// through `-r` and `-c`. Included files are looked up with resolver,
+// which can be nil if there is nothing to include from. `${VAR}`
+// references are expanded from env, nil leaves them all alone.
+func ParseFile(name string, fileContent []byte, resolver IncludeResolver, env map[string]string) (*RequirementsFile, []utils.Diagnostic) {
+	p := &fileParser{resolver: resolver, env: env, seen: map[string]bool{}, required: map[string]utils.Package{}}
+	p.parse(name, fileContent, false)
+	return &p.result, p.diags
+}
 
-	var packageStrings []string
+func (p *fileParser) parse(name string, fileContent []byte, constraint bool) {
+	p.stack = append(p.stack, name)
+	defer func() { p.stack = p.stack[:len(p.stack)-1] }()
+	if !slices.Contains(p.result.Files, name) {
+		p.result.Files = append(p.result.Files, name)
+	}
 
-	for scanner.Scan() {
-		packageStrings = append(packageStrings, scanner.Text())
+	lines, err := preprocess(fileContent, p.env)
+	if err != nil {
+		p.diags = append(p.diags, utils.NewDiagnostic(utils.Position{File: name, Line: 1, Column: 1}, utils.SeverityError,
+			utils.CodeInvalidRequirement, "Could not read all of '%s': %v", name, err))
 	}
+	for _, logical := range lines {
+		line := logical.Text
+		pos := logical.span(name, 0, len(line))
+
+		// option lines, editables are still handed to parseLine
+		if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "-e") && !strings.HasPrefix(line, "--editable") {
+			p.parseOptionLine(pos, line, constraint)
+			continue
+		}
 
-	var packageList []utils.Package
-	var errList []error
-	var details []string
-	var wg sync.WaitGroup
-	for _, pkg := range packageStrings {
-		wg.Add(1)
-		currPkg, err := parseLine(pkg, &details, &wg)
+		line, reqOpts, err := splitRequirementOptions(line)
+		if err != nil {
+			p.diags = append(p.diags, utils.NewDiagnostic(pos, utils.SeverityError, utils.CodeInvalidOption, "%v", err))
+			continue
+		}
+		pos = logical.span(name, 0, len(line))
+
+		span := func(start, end int) utils.Position { return logical.span(name, start, end) }
+		currPkg := parseLine(line, pos, span, &p.diags)
 		// we don't need empty package names, those are comments or tag reqs
 		// or it is an errored package that will be handled
 		if currPkg.Name != "" {
-			if err != nil {
-				errList = append(errList, fmt.Errorf("An error occurred parsing package: %v", err))
+			currPkg.Position = pos
+			for _, opt := range reqOpts {
+				if opt.Name == "--hash" {
+					currPkg.Hashes = append(currPkg.Hashes, opt.Value)
+				} else {
+					currPkg.InstallOptions = append(currPkg.InstallOptions, opt.Name+"="+opt.Value)
+				}
+			}
+			if constraint {
+				p.result.Constraints = append(p.result.Constraints, currPkg)
+			} else {
+				p.checkDuplicate(currPkg)
+				p.result.Requirements = append(p.result.Requirements, currPkg)
 			}
-			packageList = append(packageList, currPkg)
 		}
 	}
+}
 
-	wg.Wait()
-
-	return packageList, errList
+// checkDuplicate warns about a requirement on a package that's already
+// required for the same environments, under any spelling of its name.
+// pip merges the two, so they're either redundant or can't both hold.
+func (p *fileParser) checkDuplicate(pkg utils.Package) {
+	if pkg.Requirement == nil {
+		return
+	}
+	key := pkg.CanonicalName()
+	if pkg.Requirement.Marker != nil {
+		key += "; " + pkg.Requirement.Marker.String()
+	}
+	first, ok := p.required[key]
+	if !ok {
+		p.required[key] = pkg
+		return
+	}
+	p.diags = append(p.diags, utils.NewDiagnostic(pkg.Position, utils.SeverityWarning, utils.CodeDuplicateRequirement,
+		"'%s' is already required at %s as '%s'.", pkg.Requirement, first.Position, first.Requirement))
 }
 
-func VerifyPackages(packages []utils.Package) ([]utils.Package, []utils.Package, []string) {
-	var verifiedPackages, invalidPackages []utils.Package
-	details := []string{}
-	for _, pkg := range packages {
-		if VerifyPackage(pkg, &details) {
-			verifiedPackages = append(verifiedPackages, pkg)
-		} else {
-			invalidPackages = append(invalidPackages, pkg)
+func (p *fileParser) parseOptionLine(pos utils.Position, line string, constraint bool) {
+	opts, err := parseOptions(strings.Fields(line))
+	if err != nil {
+		p.diags = append(p.diags, utils.NewDiagnostic(pos, utils.SeverityError, utils.CodeInvalidOption, "%v", err))
+		return
+	}
+	for _, opt := range opts {
+		switch opt.Name {
+		case "--requirement":
+			// anything pulled in by a constraints file is a constraint too
+			p.include(pos, opt.Value, constraint)
+		case "--constraint":
+			p.include(pos, opt.Value, true)
+		case "--find-links":
+			// pip also looks there, but it's usually a local directory
+			// or a flat page there's no index API for
+			p.result.Options.apply(opt)
+			p.diags = append(p.diags, utils.NewDiagnostic(pos, utils.SeverityInfo, utils.CodeUnverifiable,
+				"Cannot verify packages from --find-links location '%s', only the indexes are checked.", opt.Value))
+		default:
+			if !p.result.Options.apply(opt) {
+				p.diags = append(p.diags, utils.NewDiagnostic(pos, utils.SeverityError, utils.CodeInvalidOption,
+					"unsupported option '%s'", opt.Name))
+			}
 		}
 	}
-	return verifiedPackages, invalidPackages, details
+}
+
+func (p *fileParser) include(pos utils.Position, target string, constraint bool) {
+	target = includePath(pos.File, target)
+	if slices.Contains(p.stack, target) {
+		p.diags = append(p.diags, utils.NewDiagnostic(pos, utils.SeverityError, utils.CodeIncludeCycle,
+			"include cycle: %s -> %s", strings.Join(p.stack, " -> "), target))
+		return
+	}
+
+	// the same file pulled in twice the same way adds nothing new
+	key := fmt.Sprintf("%v:%s", constraint, target)
+	if p.seen[key] {
+		return
+	}
+	p.seen[key] = true
+
+	if p.resolver == nil {
+		p.diags = append(p.diags, utils.NewDiagnostic(pos, utils.SeverityError, utils.CodeIncludeFailed,
+			"cannot read included file '%s', no other files were provided", target))
+		return
+	}
+	content, err := p.resolver.ReadFile(target)
+	if err != nil {
+		p.diags = append(p.diags, utils.NewDiagnostic(pos, utils.SeverityError, utils.CodeIncludeFailed,
+			"cannot read included file '%s': %v", target, err))
+		return
+	}
+	p.parse(target, content, constraint)
 }
@@ -0,0 +1,58 @@
+package input
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+func TestParseFileDuplicates(t *testing.T) {
+	tests := []struct {
+		content string
+		// the lines warned about, and the first requirement each repeats
+		lines []int
+		first []string
+	}{
+		{content: "Foo==1\nFOO==2\n", lines: []int{2}, first: []string{"requirements.txt:1:1"}},
+		{content: "foo_bar==1\nFoo-Bar==2\n", lines: []int{2}, first: []string{"requirements.txt:1:1"}},
+		{content: "typing_extensions\nflask\ntyping-extensions>=4\nTyping.Extensions\n", lines: []int{3, 4}, first: []string{"requirements.txt:1:1", "requirements.txt:1:1"}},
+		// the same marker is the same environments
+		{content: "foo; python_version < '3.8'\nFOO>=2 ; python_version<\"3.8\"\n", lines: []int{2}, first: []string{"requirements.txt:1:1"}},
+		// different markers, different environments
+		{content: "foo==1; python_version < '3.8'\nfoo==2; python_version >= '3.8'\n"},
+		{content: "foo\nbar\nfoo-bar\n"},
+	}
+	for _, test := range tests {
+		_, diags := ParseFile("requirements.txt", []byte(test.content), nil, nil)
+		lines := []int{}
+		for _, diag := range diags {
+			if diag.Code != utils.CodeDuplicateRequirement {
+				continue
+			}
+			if len(lines) < len(test.first) && !strings.Contains(diag.Message, test.first[len(lines)]) {
+				t.Errorf("%q: %v doesn't point at %s", test.content, diag, test.first[len(lines)])
+			}
+			lines = append(lines, diag.Line)
+		}
+		if !slices.Equal(lines, test.lines) {
+			t.Errorf("%q: duplicates on lines %v, want %v", test.content, lines, test.lines)
+		}
+	}
+}
+
+func TestParseFileDuplicatesAcrossIncludes(t *testing.T) {
+	includes := MapResolver{"base.txt": []byte("flask\nPyYAML==6.0\n")}
+	_, parseDiags := ParseFile("requirements.txt", []byte("-r base.txt\npyyaml==6.0.1\n"), includes, nil)
+	diags := []utils.Diagnostic{}
+	for _, diag := range parseDiags {
+		if diag.Code != utils.CodeNonCanonicalName {
+			diags = append(diags, diag)
+		}
+	}
+	if len(diags) != 1 || diags[0].Code != utils.CodeDuplicateRequirement || diags[0].File != "requirements.txt" || diags[0].Line != 2 ||
+		!strings.Contains(diags[0].Message, "base.txt:2:1") {
+		t.Errorf("diagnostics = %v, want an RQ030 on requirements.txt:2 pointing at base.txt:2", diags)
+	}
+}
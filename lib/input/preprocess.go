@@ -0,0 +1,177 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+// these are the same patterns pip uses in req_file.py
+var (
+	commentRe = regexp.MustCompile(`(^|\s+)#.*$`)
+	envVarRe  = regexp.MustCompile(`\$\{([A-Z0-9_]+)\}`)
+)
+
+// logicalLine is one requirement or option after continuations are
+// joined, Num is the line it started on in the original file and Col
+// the column its text starts at, counting through the joined lines.
+type logicalLine struct {
+	Num  int
+	Col  int
+	Text string
+	// where each byte of Text, and its end, was before ${VAR} expansion,
+	// nil when nothing was expanded
+	offsets []int
+	// the physical lines joined into it, in order
+	pieces []piece
+}
+
+// piece is one physical line of a logical line, start is where its text
+// begins in the joined line and lead how many bytes were cut off its
+// front.
+type piece struct {
+	num, start, lead int
+}
+
+// column is the column in the joined line of byte i of Text. Anything
+// in an expanded value points at the start of its `${VAR}`, and the
+// byte after it at the end.
+func (l logicalLine) column(i int) int {
+	if l.offsets == nil {
+		return l.Col + i
+	}
+	if last := len(l.offsets) - 1; i > last {
+		return l.Col + l.offsets[last] + i - last
+	}
+	return l.Col + l.offsets[i]
+}
+
+// locate is the physical line byte i of Text is on and its column
+// there. A byte right where a continuation starts is the start of the
+// next line, unless it's the end of a span, which ends the line before.
+func (l logicalLine) locate(i int, end bool) (line, col int) {
+	offset := l.column(i) - 1
+	k := 0
+	for k+1 < len(l.pieces) && (offset > l.pieces[k+1].start || !end && offset == l.pieces[k+1].start) {
+		k++
+	}
+	p := l.pieces[k]
+	return p.num, p.lead + offset - p.start + 1
+}
+
+// span is where bytes start to end of Text are in file. A Position is on
+// one line, so a span running over a continuation stops at the end of
+// the line it starts on.
+func (l logicalLine) span(file string, start, end int) utils.Position {
+	line, col := l.locate(start, false)
+	endLine, endCol := l.locate(end, true)
+	if endLine != line {
+		for k, p := range l.pieces {
+			if p.num == line && k+1 < len(l.pieces) {
+				endCol = p.lead + l.pieces[k+1].start - p.start + 1
+			}
+		}
+	}
+	return utils.Position{File: file, Line: line, Column: col, EndColumn: endCol}
+}
+
+// preprocess does what pip does to a requirements file before it
+// parses anything: it joins lines ending in `\`, drops comments, which
+// need whitespace before the `#` so `#egg=` and `#sha256=` survive, and
+// expands `${VAR}` from env. Unknown and empty variables are left as
+// they are.
+// The lines read before an error come back along with it.
+func preprocess(fileContent []byte, env map[string]string) ([]logicalLine, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(fileContent))
+	scanner.Split(bufio.ScanLines)
+	// no line is longer than the file, the default 64 KiB limit would
+	// stop at a long --hash list
+	scanner.Buffer(nil, len(fileContent)+1)
+
+	lines := []logicalLine{}
+	var joined *logicalLine
+	num := 0
+	for scanner.Scan() {
+		num++
+		line := scanner.Text()
+		if joined == nil {
+			joined = &logicalLine{Num: num}
+		}
+
+		// a comment ending in `\` doesn't continue, and it gets a space
+		// in front so it still reads as a comment after joining
+		isComment := commentRe.MatchString(line) && commentRe.FindStringIndex(line)[0] == 0
+		if !strings.HasSuffix(line, "\\") || isComment {
+			lead := 0
+			if isComment {
+				line = " " + line
+				lead = -1
+			}
+			joined.pieces = append(joined.pieces, piece{num: num, start: len(joined.Text), lead: lead})
+			joined.Text += line
+			lines = append(lines, *joined)
+			joined = nil
+			continue
+		}
+
+		text := strings.Trim(line, "\\")
+		joined.pieces = append(joined.pieces, piece{num: num, start: len(joined.Text), lead: len(line) - len(strings.TrimLeft(line, "\\"))})
+		joined.Text += text
+	}
+	var err error
+	if scanErr := scanner.Err(); scanErr != nil {
+		err = fmt.Errorf("stopped reading after line %d: %v", num, scanErr)
+	}
+	// the last line ended with `\`
+	if joined != nil {
+		lines = append(lines, *joined)
+	}
+
+	result := []logicalLine{}
+	for _, line := range lines {
+		text := commentRe.ReplaceAllString(line.Text, "")
+		col := len(text) - len(strings.TrimLeft(text, " \t")) + 1
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		text, offsets := expandEnv(text, env)
+		result = append(result, logicalLine{Num: line.Num, Col: col, Text: text, offsets: offsets, pieces: line.pieces})
+	}
+	return result, err
+}
+
+// expandEnv replaces the `${VAR}` references env has a non-empty value
+// for, and gives back the offsets logicalLine.column maps through.
+func expandEnv(text string, env map[string]string) (string, []int) {
+	var sb strings.Builder
+	offsets := []int{}
+	last := 0
+	for _, match := range envVarRe.FindAllStringSubmatchIndex(text, -1) {
+		value := env[text[match[2]:match[3]]]
+		if value == "" {
+			continue
+		}
+		for i := last; i < match[0]; i++ {
+			offsets = append(offsets, i)
+		}
+		for range len(value) {
+			offsets = append(offsets, match[0])
+		}
+		sb.WriteString(text[last:match[0]])
+		sb.WriteString(value)
+		last = match[1]
+	}
+	if last == 0 {
+		return text, nil
+	}
+	for i := last; i <= len(text); i++ {
+		offsets = append(offsets, i)
+	}
+	sb.WriteString(text[last:])
+	return sb.String(), offsets
+}
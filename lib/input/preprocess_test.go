@@ -0,0 +1,128 @@
+package input
+
+import (
+	"strings"
+	"testing"
+
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+func TestPreprocess(t *testing.T) {
+	content := "# header\nfoo \\\n  ==1.0  # pinned\n\n  bar>=2 \\\n"
+	lines, err := preprocess([]byte(content), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []logicalLine{{Num: 2, Col: 1, Text: "foo   ==1.0"}, {Num: 5, Col: 3, Text: "bar>=2"}}
+	if len(lines) != len(want) {
+		t.Fatalf("preprocess() = %+v, want %+v", lines, want)
+	}
+	for i, line := range lines {
+		if line.Num != want[i].Num || line.Col != want[i].Col || line.Text != want[i].Text {
+			t.Errorf("line %d = %+v, want %+v", i, line, want[i])
+		}
+	}
+}
+
+func TestPreprocessLongLines(t *testing.T) {
+	hashes := strings.Repeat(" --hash=sha256:"+strings.Repeat("a", 64), 1200)
+	lines, err := preprocess([]byte("demo==1.0"+hashes+"\nother\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || lines[1].Text != "other" {
+		t.Errorf("preprocess() dropped the lines after a %d byte one", len(hashes))
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{"VER": "1.0.0", "EMPTY": ""}
+	tests := []struct {
+		text, want string
+	}{
+		{"demo==${VER}", "demo==1.0.0"},
+		// pip leaves unset and empty variables alone
+		{"demo==${UNSET}", "demo==${UNSET}"},
+		{"demo${EMPTY}==${VER}", "demo${EMPTY}==1.0.0"},
+		{"demo==$VER", "demo==$VER"},
+	}
+	for _, test := range tests {
+		if got, _ := expandEnv(test.text, env); got != test.want {
+			t.Errorf("expandEnv(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestEnvColumns(t *testing.T) {
+	env := map[string]string{"VER": "1.0.0", "NAME": "Foo_Bar", "EMPTY": ""}
+	tests := []struct {
+		line string
+		// the columns of the pin, and of the name when it's not canonical
+		pin, name [2]int
+	}{
+		{line: "  demo==${VER}  # c", pin: [2]int{9, 15}},
+		{line: "${NAME}==1.0", pin: [2]int{10, 13}, name: [2]int{1, 8}},
+		{line: "${NAME}==${VER}  # ${EMPTY}", pin: [2]int{10, 16}, name: [2]int{1, 8}},
+	}
+	for _, test := range tests {
+		reqFile, diags := ParseFile("requirements.txt", []byte(test.line), nil, env)
+		if len(reqFile.Requirements) != 1 {
+			t.Fatalf("%q: got %+v", test.line, reqFile.Requirements)
+		}
+		pin := reqFile.Requirements[0].PinPosition
+		if pin.Column != test.pin[0] || pin.EndColumn != test.pin[1] {
+			t.Errorf("%q: pin at columns %d-%d, want %d-%d", test.line, pin.Column, pin.EndColumn, test.pin[0], test.pin[1])
+		}
+		for _, diag := range diags {
+			if diag.Code == utils.CodeNonCanonicalName && (diag.Column != test.name[0] || diag.EndColumn != test.name[1]) {
+				t.Errorf("%q: name at columns %d-%d, want %d-%d", test.line, diag.Column, diag.EndColumn, test.name[0], test.name[1])
+			}
+		}
+	}
+}
+
+func TestContinuationPositions(t *testing.T) {
+	tests := []struct {
+		content string
+		// the requirement, as far as its first line goes, and its pin
+		pos, pin utils.Position
+		// the first diagnostic, if any
+		diag utils.Position
+	}{
+		{
+			content: "demo \\\n  ==1.0\n",
+			pos:     utils.Position{File: "requirements.txt", Line: 1, Column: 1, EndColumn: 6},
+			pin:     utils.Position{File: "requirements.txt", Line: 2, Column: 5, EndColumn: 8},
+		},
+		{
+			// pip drops the backslashes at the front of a continued line too
+			content: "# pins\n  demo\\\n\\\\==1.0\\\n --hash=sha256:abc\n",
+			pos:     utils.Position{File: "requirements.txt", Line: 2, Column: 3, EndColumn: 7},
+			pin:     utils.Position{File: "requirements.txt", Line: 3, Column: 5, EndColumn: 8},
+		},
+		{
+			content: "Foo_Bar \\\n  >=1.0\n",
+			pos:     utils.Position{File: "requirements.txt", Line: 1, Column: 1, EndColumn: 9},
+			diag:    utils.Position{File: "requirements.txt", Line: 1, Column: 1, EndColumn: 8},
+		},
+		{
+			content: "demo \\\n  >=1.0 ; python_version >= \n",
+			pos:     utils.Position{File: "requirements.txt", Line: 1, Column: 1, EndColumn: 6},
+			diag:    utils.Position{File: "requirements.txt", Line: 2, Column: 28, EndColumn: 28},
+		},
+	}
+	for _, test := range tests {
+		reqFile, diags := ParseFile("requirements.txt", []byte(test.content), nil, nil)
+		var pos, pin, diag utils.Position
+		if len(reqFile.Requirements) > 0 {
+			pos, pin = reqFile.Requirements[0].Position, reqFile.Requirements[0].PinPosition
+		}
+		if len(diags) > 0 {
+			diag = diags[0].Position
+		}
+		if pos != test.pos || pin != test.pin || diag != test.diag {
+			t.Errorf("%q: requirement at %+v, pin at %+v, diagnostic at %+v, want %+v, %+v, %+v",
+				test.content, pos, pin, diag, test.pos, test.pin, test.diag)
+		}
+	}
+}
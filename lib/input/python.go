@@ -0,0 +1,162 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	"github.com/DerekCorniello/pip-req-valid/pep440"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+// ParsePythons reads a comma separated list of interpreter versions,
+// like `3.8,3.9,3.10,3.11,3.12,3.13`.
+func ParsePythons(s string) ([]string, error) {
+	pythons := []string{}
+	for _, python := range strings.Split(s, ",") {
+		python = strings.TrimSpace(python)
+		if python == "" {
+			continue
+		}
+		if _, err := pep440.Parse(python); err != nil {
+			return nil, fmt.Errorf("invalid python version '%s'", python)
+		}
+		pythons = append(pythons, python)
+	}
+	return pythons, nil
+}
+
+// pythonVersion is the interpreter version Requires-Python gets checked
+// against. `3.11` means the newest 3.11 we know of, since that's what
+// anyone installing on 3.11 today gets.
+func pythonVersion(python string) (*pep440.Version, error) {
+	if full, ok := pythonReleases[python]; ok {
+		python = full
+	}
+	v, err := pep440.Parse(python)
+	if err != nil {
+		return nil, fmt.Errorf("invalid python version '%s'", python)
+	}
+	return v, nil
+}
+
+// targetPython is the interpreter of a target, from python_full_version
+// or failing that python_version. Custom targets don't have to set
+// either.
+func targetPython(target Target) (*pep440.Version, error) {
+	for _, key := range []string{"python_full_version", "python_version"} {
+		if python, ok := target.Env[key]; ok {
+			return pythonVersion(python)
+		}
+	}
+	return nil, fmt.Errorf("target '%s' sets neither python_full_version nor python_version", target.Name)
+}
+
+// supportsPython checks a Requires-Python value, like pip anything that
+// can't be parsed doesn't rule the version out.
+func supportsPython(requiresPython string, python *pep440.Version) bool {
+	set, err := pep440.ParseSpecifierSet(strings.ReplaceAll(requiresPython, " ", ""))
+	if err != nil {
+		return true
+	}
+	return set.Contains(python, true)
+}
+
+// checkPythons works out what pip would install for the package on each
+// interpreter, and reports the ones it can't be installed on at all.
+func checkPythons(ctx context.Context, pkg utils.Package, opts GlobalOptions, lookup *versionLookup, pythons []string, diags *[]utils.Diagnostic) ([]utils.PythonSupport, bool) {
+	// urls and local refs have no index metadata to go by
+	versions, allowed, ok := installCandidates(ctx, pkg, opts, lookup)
+	if !ok {
+		return nil, true
+	}
+	specs := strings.Join(pkg.Requirement.SpecifierStrings(), ",")
+
+	byVersion := map[string]index.Version{}
+	for _, version := range versions {
+		byVersion[version.Version] = version
+	}
+	// newest first, the order pip tries them in
+	slices.Reverse(allowed)
+	// anything can be suggested, but a pre-release only when pip would
+	// pick one
+	specSet, _ := pep440.ParseSpecifierSet(specs)
+	pre := opts.Pre || specSet.Prereleases()
+	everything := []*pep440.Version{}
+	for _, v := range parseVersions(versions, false) {
+		if !v.IsPrerelease() || pre {
+			everything = append(everything, v)
+		}
+	}
+	pep440.Sort(everything)
+	slices.Reverse(everything)
+
+	support := []utils.PythonSupport{}
+	for _, python := range pythons {
+		cell := utils.PythonSupport{Python: python}
+		interpreter, err := pythonVersion(python)
+		if err != nil {
+			*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityWarning, utils.CodeUnverifiable,
+				"Cannot check '%s' against Python %s: %v.", pkg.Name, python, err))
+			support = append(support, cell)
+			continue
+		}
+		for _, v := range allowed {
+			if requires := byVersion[v.Original()].RequiresPython; supportsPython(requires, interpreter) {
+				cell.Version, cell.RequiresPython, cell.Compatible = v.Original(), requires, true
+				break
+			}
+		}
+		if !cell.Compatible {
+			cell.RequiresPython = byVersion[allowed[0].Original()].RequiresPython
+			for _, v := range everything {
+				if supportsPython(byVersion[v.Original()].RequiresPython, interpreter) {
+					cell.Suggestion = v.Original()
+					break
+				}
+			}
+
+			msg := fmt.Sprintf("No version of package '%s' allowed by '%s' supports Python %s, %s requires Python '%s'",
+				pkg.Name, specs, python, allowed[0].Original(), cell.RequiresPython)
+			if cell.Suggestion != "" {
+				msg += fmt.Sprintf("; the newest version that does is %s", cell.Suggestion)
+			}
+			*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodePythonIncompatible, "%s.", msg))
+			ok = false
+		}
+		support = append(support, cell)
+	}
+	return support, ok
+}
+
+// PythonMatrix is package by interpreter, each cell saying what pip
+// would install there.
+type PythonMatrix struct {
+	Pythons []string          `json:"pythons"`
+	Rows    []PythonMatrixRow `json:"rows"`
+}
+
+type PythonMatrixRow struct {
+	Package string `json:"package"`
+	utils.Position
+	// one per interpreter, in the same order as PythonMatrix.Pythons
+	Cells []utils.PythonSupport `json:"cells"`
+}
+
+// BuildPythonMatrix lays out what VerifyPackages found for the
+// interpreters in config.Pythons, in the order of files. Packages
+// without any index metadata are left out.
+func BuildPythonMatrix(pythons []string, packages []utils.Package, files utils.FileOrder) *PythonMatrix {
+	matrix := &PythonMatrix{Pythons: pythons, Rows: []PythonMatrixRow{}}
+	for _, pkg := range packages {
+		if len(pkg.Pythons) > 0 {
+			matrix.Rows = append(matrix.Rows, PythonMatrixRow{Package: pkg.Name, Position: pkg.Position, Cells: pkg.Pythons})
+		}
+	}
+	// verified and invalid packages come in separately, put the rows
+	// back in file order
+	utils.SortByPosition(matrix.Rows, files, func(row PythonMatrixRow) utils.Position { return row.Position })
+	return matrix
+}
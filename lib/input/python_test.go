@@ -0,0 +1,90 @@
+package input
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+func TestTargetPython(t *testing.T) {
+	tests := []struct {
+		env  pep508.Environment
+		want string
+	}{
+		{pep508.Environment{"python_full_version": "3.9.2", "python_version": "3.9"}, "3.9.2"},
+		{pep508.Environment{"python_version": "3.11"}, pythonReleases["3.11"]},
+		{pep508.Environment{"sys_platform": "linux"}, ""},
+		{pep508.Environment{"python_full_version": "three"}, ""},
+	}
+	for _, test := range tests {
+		got, err := targetPython(Target{Name: "custom", Env: test.env})
+		switch {
+		case test.want == "" && err == nil:
+			t.Errorf("targetPython(%v) = %s, want an error", test.env, got)
+		case test.want != "" && (err != nil || got.Original() != test.want):
+			t.Errorf("targetPython(%v) = %v, %v, want %s", test.env, got, err, test.want)
+		}
+	}
+}
+
+func TestBadPythonsAreReported(t *testing.T) {
+	mem := index.Memory{"demo": {Name: "demo", Releases: map[string]*index.ProjectRelease{
+		"1.0": {Release: index.Release{RequiresPython: ">=3.8"}, Files: []index.File{{Filename: "demo-1.0-py3-none-any.whl", RequiresPython: ">=3.8"}}},
+	}}}
+	reqFile, _ := ParseFile("requirements.txt", []byte("demo\n"), nil, nil)
+	pkg := reqFile.Requirements[0]
+	pkg.Targets = []string{"custom"}
+	lookup := newVersionLookup(VerifyConfig{Index: mem}, reqFile.Options)
+
+	diags := []utils.Diagnostic{}
+	support, ok := checkPythons(context.Background(), pkg, reqFile.Options, lookup, []string{"3.11", "not-a-version"}, &diags)
+	if !ok || len(support) != 2 || !support[0].Compatible || support[1].Compatible {
+		t.Errorf("checkPythons() = %+v, %v, want 3.11 supported and the bad entry skipped", support, ok)
+	}
+	if len(diags) != 1 || diags[0].Code != utils.CodeUnverifiable {
+		t.Errorf("checkPythons() diagnostics = %v, want one RQ008", diags)
+	}
+
+	diags = []utils.Diagnostic{}
+	custom := Target{Name: "custom", Env: pep508.Environment{"python_full_version": "three", "python_version": "3.11", "sys_platform": "linux"}, Platforms: TargetProfiles[DefaultTarget].Platforms}
+	if _, ok := checkWheels(context.Background(), pkg, reqFile.Options, lookup, []Target{custom}, &diags); !ok {
+		t.Errorf("checkWheels() failed for a target with a bad python version")
+	}
+	if len(diags) != 1 || diags[0].Code != utils.CodeUnverifiable {
+		t.Errorf("checkWheels() diagnostics = %v, want one RQ008", diags)
+	}
+}
+
+func TestPythonSuggestion(t *testing.T) {
+	release := func(requiresPython string) *index.ProjectRelease {
+		return &index.ProjectRelease{
+			Release: index.Release{RequiresPython: requiresPython},
+			Files:   []index.File{{Filename: "demo-py3-none-any.whl", RequiresPython: requiresPython}},
+		}
+	}
+	mem := index.Memory{"demo": {Name: "demo", Releases: map[string]*index.ProjectRelease{
+		"1.0":    release(">=3.7"),
+		"1.5rc1": release(">=3.7"),
+		"2.0":    release(">=3.10"),
+	}}}
+	tests := []struct {
+		content, want string
+	}{
+		{"demo>=2\n", "1.0"},
+		{"--pre\ndemo>=2\n", "1.5rc1"},
+		// naming a pre-release lets pip pick them too
+		{"demo>=2.0rc1\n", "1.5rc1"},
+	}
+	for _, test := range tests {
+		reqFile, _ := ParseFile("requirements.txt", []byte(test.content), nil, nil)
+		lookup := newVersionLookup(VerifyConfig{Index: mem}, reqFile.Options)
+		diags := []utils.Diagnostic{}
+		support, ok := checkPythons(context.Background(), reqFile.Requirements[0], reqFile.Options, lookup, []string{"3.8"}, &diags)
+		if ok || len(support) != 1 || support[0].Suggestion != test.want {
+			t.Errorf("%q: checkPythons() = %+v, %v, want %s suggested", test.content, support, ok, test.want)
+		}
+	}
+}
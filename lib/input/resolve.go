@@ -0,0 +1,108 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	"github.com/DerekCorniello/pip-req-valid/lock"
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+	"github.com/DerekCorniello/pip-req-valid/resolve"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+// ResolveRequirements runs the full resolver over the file for each of
+// config's targets, giving back the pinned set for every target that
+// resolves. Bare urls and local refs can't be resolved and are left
+// out, they were already reported while parsing.
+func ResolveRequirements(ctx context.Context, reqFile *RequirementsFile, config VerifyConfig) (map[string]*resolve.Resolution, []utils.Diagnostic) {
+	return resolveRequirements(ctx, reqFile, config, config.packageIndex(reqFile.Options))
+}
+
+func resolveRequirements(ctx context.Context, reqFile *RequirementsFile, config VerifyConfig, idx index.PackageIndex) (map[string]*resolve.Resolution, []utils.Diagnostic) {
+	resolver := &resolve.Resolver{Index: idx, Pre: reqFile.Options.Pre}
+	for _, pkg := range reqFile.Constraints {
+		if pkg.Requirement != nil {
+			resolver.Constraints = append(resolver.Constraints, resolve.Root{Requirement: pkg.Requirement, Source: pkg.File})
+		}
+	}
+	roots := []resolve.Root{}
+	positions := map[*pep508.Requirement]utils.Position{}
+	for _, pkg := range reqFile.Requirements {
+		if pkg.Requirement != nil {
+			roots = append(roots, resolve.Root{Requirement: pkg.Requirement, Source: pkg.File})
+			positions[pkg.Requirement] = pkg.Position
+		}
+	}
+
+	resolutions := map[string]*resolve.Resolution{}
+	// the same conflict usually shows up for every target, report it
+	// once with all of them
+	failed := map[string][]string{}
+	failures := []string{}
+	errPositions := map[string]utils.Position{}
+	// errors that aren't conflicts, like metadata the index couldn't
+	// give, say nothing about the requirements themselves
+	notConflicts := map[string]bool{}
+	for _, target := range config.targets() {
+		resolution, err := resolver.Resolve(ctx, roots, target.Env)
+		if err == nil {
+			resolutions[target.Name] = resolution
+			continue
+		}
+		msg := err.Error()
+		if _, ok := failed[msg]; !ok {
+			failures = append(failures, msg)
+			var conflict *resolve.Conflict
+			if !errors.As(err, &conflict) {
+				notConflicts[msg] = true
+			} else if len(conflict.Roots()) > 0 {
+				errPositions[msg] = positions[conflict.Roots()[0]]
+			}
+		}
+		failed[msg] = append(failed[msg], target.Name)
+	}
+
+	diags := []utils.Diagnostic{}
+	for _, msg := range failures {
+		targets := strings.Join(failed[msg], ", ")
+		if notConflicts[msg] {
+			diags = append(diags, utils.NewDiagnostic(utils.Position{}, utils.SeverityError, utils.CodeIndexError,
+				"Could not resolve requirements on %s: %s", targets, msg))
+			continue
+		}
+		diags = append(diags, utils.NewDiagnostic(errPositions[msg], utils.SeverityError, utils.CodeResolutionImpossible,
+			"Requirements cannot be installed together on %s: %s", targets, msg))
+	}
+	return resolutions, diags
+}
+
+// LockRequirements resolves the file for each of config's targets and
+// pins every package it installs, hashes included, along with the
+// file's index options. Targets that don't resolve, or have a package
+// that can't be hashed, get no lockfile, only the diagnostic saying why.
+func LockRequirements(ctx context.Context, reqFile *RequirementsFile, config VerifyConfig) (map[string]*lock.Lockfile, []utils.Diagnostic) {
+	idx := config.packageIndex(reqFile.Options)
+	resolutions, diags := resolveRequirements(ctx, reqFile, config, idx)
+	lockfiles := map[string]*lock.Lockfile{}
+	for _, target := range config.targets() {
+		resolution, ok := resolutions[target.Name]
+		if !ok {
+			continue
+		}
+		lockfile, err := lock.Build(ctx, idx, target.Name, resolution)
+		if err != nil {
+			code := utils.CodeIndexError
+			if errors.Is(err, lock.ErrMissingHash) {
+				code = utils.CodeMissingHash
+			}
+			diags = append(diags, utils.NewDiagnostic(utils.Position{}, utils.SeverityError, code,
+				"Could not lock requirements for %s: %v", target.Name, err))
+			continue
+		}
+		lockfile.SetIndexes(reqFile.Options.IndexURL, reqFile.Options.ExtraIndexURLs)
+		lockfiles[target.Name] = lockfile
+	}
+	return lockfiles, diags
+}
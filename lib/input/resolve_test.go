@@ -0,0 +1,78 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+func TestResolveRequirementsConflict(t *testing.T) {
+	project := func(name, version string, requires ...string) *index.Project {
+		return &index.Project{Name: name, Releases: map[string]*index.ProjectRelease{
+			version: {Release: index.Release{Name: name, Version: version, RequiresDist: requires}},
+		}}
+	}
+	mem := index.Memory{
+		"flask": project("flask", "3.0.0"),
+		"x":     project("x", "1.0", "lib"),
+		"a":     project("a", "1.0", "lib>=2"),
+		"b":     project("b", "1.0", "lib<2"),
+		"lib":   {Name: "lib", Releases: map[string]*index.ProjectRelease{"1.0": {}, "2.0": {}}},
+	}
+	// x needs lib as well, but only a and b conflict
+	content := "flask\nx\na==1.0\nb\n"
+	reqFile, _ := ParseFile("requirements.txt", []byte(content), nil, nil)
+
+	resolutions, diags := ResolveRequirements(context.Background(), reqFile, VerifyConfig{Index: mem})
+	if len(resolutions) != 0 {
+		t.Errorf("resolutions = %v, want none", resolutions)
+	}
+	if len(diags) != 1 || diags[0].Code != utils.CodeResolutionImpossible {
+		t.Fatalf("diagnostics = %v, want one RQ014", diags)
+	}
+	diag := diags[0]
+	for _, want := range []string{"requirements.txt -> a==1.0 -> lib>=2", "requirements.txt -> b==1.0 -> lib<2", "can't be installed together: a==1.0, b"} {
+		if !strings.Contains(diag.Message, want) {
+			t.Errorf("explanation %q doesn't mention %q", diag.Message, want)
+		}
+	}
+	for _, unrelated := range []string{"x==1.0", "flask"} {
+		if strings.Contains(diag.Message, unrelated) {
+			t.Errorf("explanation %q mentions %s, which has nothing to do with it", diag.Message, unrelated)
+		}
+	}
+	if diag.Line != 3 {
+		t.Errorf("diagnostic on line %d, want the first conflicting requirement on line 3", diag.Line)
+	}
+}
+
+func TestLockRequirementsWithoutMetadata(t *testing.T) {
+	// a simple index that lists flask but serves no .metadata for it
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasSuffix(req.URL.Path, "/flask/") {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.pypi.simple.v1+json")
+		fmt.Fprint(w, `{"name": "flask", "files": [{"filename": "flask-3.0.0-py3-none-any.whl", "url": "/files/flask-3.0.0-py3-none-any.whl", "hashes": {"sha256": "aaa"}}]}`)
+	}))
+	defer server.Close()
+
+	reqFile, _ := ParseFile("requirements.txt", []byte("flask==3.0.0\n"), nil, nil)
+	config := VerifyConfig{Index: index.NewSimple(server.URL+"/simple", nil, nil)}
+	lockfiles, diags := LockRequirements(context.Background(), reqFile, config)
+
+	// flask's dependencies would be missing from it
+	if len(lockfiles) != 0 {
+		t.Errorf("lockfiles = %v, want none", lockfiles)
+	}
+	if len(diags) != 1 || diags[0].Severity != utils.SeverityError || !strings.Contains(diags[0].Message, "flask==3.0.0") {
+		t.Errorf("diagnostics = %v, want an error about flask's metadata", diags)
+	}
+}
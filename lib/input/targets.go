@@ -0,0 +1,200 @@
+package input
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+// Target is one environment the requirements get installed into, the
+// markers in the file are evaluated against each of them.
+type Target struct {
+	Name string             `json:"name"`
+	Env  pep508.Environment `json:"env"`
+	// the wheel platform tags that install there, like
+	// `manylinux_2_17_x86_64`
+	Platforms []string `json:"platforms"`
+}
+
+// DefaultTarget matches the python:3.11-slim image the test install
+// runs in.
+const DefaultTarget = "py3.11-linux-x86_64"
+
+// the newest patch release of each python we have a profile for
+var pythonReleases = map[string]string{
+	"3.8":  "3.8.20",
+	"3.9":  "3.9.20",
+	"3.10": "3.10.15",
+	"3.11": "3.11.10",
+	"3.12": "3.12.7",
+	"3.13": "3.13.0",
+}
+
+type platform struct {
+	osName         string
+	sysPlatform    string
+	platformSystem string
+	machine        string
+	wheelPlatforms []string
+}
+
+// linux is glibc 2.36 like debian bookworm, which the slim images are
+// built on, alpine is musl 1.2 and macos is 14
+var platforms = map[string]platform{
+	"linux-x86_64":   {"posix", "linux", "Linux", "x86_64", manylinux("x86_64", 36)},
+	"linux-aarch64":  {"posix", "linux", "Linux", "aarch64", manylinux("aarch64", 36)},
+	"alpine-x86_64":  {"posix", "linux", "Linux", "x86_64", musllinux("x86_64", 2)},
+	"alpine-aarch64": {"posix", "linux", "Linux", "aarch64", musllinux("aarch64", 2)},
+	"macos-x86_64":   {"posix", "darwin", "Darwin", "x86_64", macosx("x86_64", 14)},
+	"macos-arm64":    {"posix", "darwin", "Darwin", "arm64", macosx("arm64", 14)},
+	"windows-amd64":  {"nt", "win32", "Windows", "AMD64", []string{"win_amd64"}},
+}
+
+// manylinux lists the PEP 600 tags a glibc 2.<glibc> system takes, and
+// the older PEP 513/571/599 names that are aliases for some of them.
+func manylinux(arch string, glibc int) []string {
+	tags := []string{}
+	for minor := glibc; minor >= 5; minor-- {
+		tags = append(tags, fmt.Sprintf("manylinux_2_%d_%s", minor, arch))
+		switch {
+		case minor == 17:
+			tags = append(tags, "manylinux2014_"+arch)
+		case minor == 12 && arch == "x86_64":
+			tags = append(tags, "manylinux2010_"+arch)
+		case minor == 5 && arch == "x86_64":
+			tags = append(tags, "manylinux1_"+arch)
+		}
+	}
+	return append(tags, "linux_"+arch)
+}
+
+// musllinux lists the PEP 656 tags a musl 1.<musl> system takes.
+func musllinux(arch string, musl int) []string {
+	tags := []string{}
+	for minor := musl; minor >= 0; minor-- {
+		tags = append(tags, fmt.Sprintf("musllinux_1_%d_%s", minor, arch))
+	}
+	return append(tags, "linux_"+arch)
+}
+
+// macosx lists the tags of every macos release up to major, wheels
+// built for an older release still load on a newer one.
+func macosx(arch string, major int) []string {
+	binaries := []string{arch, "universal2"}
+	if arch == "x86_64" {
+		binaries = append(binaries, "intel", "universal")
+	}
+	versions := []string{}
+	for v := major; v >= 11; v-- {
+		versions = append(versions, fmt.Sprintf("%d_0", v))
+	}
+	// apple silicon only exists from 11 on
+	if arch == "x86_64" {
+		for minor := 16; minor >= 9; minor-- {
+			versions = append(versions, fmt.Sprintf("10_%d", minor))
+		}
+	}
+	tags := []string{}
+	for _, version := range versions {
+		for _, binary := range binaries {
+			tags = append(tags, "macosx_"+version+"_"+binary)
+		}
+	}
+	return tags
+}
+
+// TargetProfiles has a CPython target for every python and platform
+// pair above, named like `py3.12-windows-amd64`.
+var TargetProfiles = buildTargetProfiles()
+
+func buildTargetProfiles() map[string]Target {
+	profiles := map[string]Target{}
+	for pythonVersion, fullVersion := range pythonReleases {
+		for platformName, p := range platforms {
+			name := fmt.Sprintf("py%s-%s", pythonVersion, platformName)
+			profiles[name] = Target{Name: name, Env: pep508.Environment{
+				"python_version":                 pythonVersion,
+				"python_full_version":            fullVersion,
+				"os_name":                        p.osName,
+				"sys_platform":                   p.sysPlatform,
+				"platform_system":                p.platformSystem,
+				"platform_machine":               p.machine,
+				"platform_release":               "",
+				"platform_version":               "",
+				"platform_python_implementation": "CPython",
+				"implementation_name":            "cpython",
+				"implementation_version":         fullVersion,
+			}, Platforms: p.wheelPlatforms}
+		}
+	}
+	return profiles
+}
+
+// ParseTargets looks up a comma separated list of profile names, like
+// `py3.12-linux-x86_64,py3.12-windows-amd64`.
+func ParseTargets(names string) ([]Target, error) {
+	targets := []Target{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		target, ok := TargetProfiles[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown target '%s', expected one of %s", name, strings.Join(profileNames(), ", "))
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// matchTargets sets pkg.Targets to the names of the targets the
+// package's marker holds in, and reports the ones it's left out of.
+func matchTargets(pkg *utils.Package, targets []Target, diags *[]utils.Diagnostic) {
+	pkg.Targets = []string{}
+	for _, target := range targets {
+		applies := true
+		if pkg.Requirement != nil {
+			var err error
+			applies, err = pkg.Requirement.Applies(target.Env)
+			if err != nil {
+				// better to check it everywhere than to drop it
+				*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityWarning, utils.CodeInvalidMarker,
+					"Could not evaluate marker '%s' of '%s' for target '%s': %v", pkg.EnvMarker, pkg.Name, target.Name, err))
+				pkg.Targets = targetNames(targets)
+				return
+			}
+		}
+		if applies {
+			pkg.Targets = append(pkg.Targets, target.Name)
+		}
+	}
+
+	if len(pkg.Targets) == 0 {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityInfo, utils.CodeNotApplicable,
+			"Package '%s' does not apply to any target because of '%s', skipping it.", pkg.Name, pkg.EnvMarker))
+	} else if len(pkg.Targets) < len(targets) {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityInfo, utils.CodeNotApplicable,
+			"Package '%s' only applies to %s.", pkg.Name, strings.Join(pkg.Targets, ", ")))
+	}
+}
+
+func targetNames(targets []Target) []string {
+	names := []string{}
+	for _, target := range targets {
+		names = append(names, target.Name)
+	}
+	return names
+}
+
+func profileNames() []string {
+	names := []string{}
+	for name := range TargetProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
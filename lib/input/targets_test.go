@@ -0,0 +1,56 @@
+package input
+
+import (
+	"slices"
+	"testing"
+
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+func TestMatchTargets(t *testing.T) {
+	targets, err := ParseTargets("py3.8-linux-x86_64,py3.12-linux-x86_64,py3.12-windows-amd64,py3.12-macos-arm64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := []string{"py3.8-linux-x86_64", "py3.12-linux-x86_64", "py3.12-windows-amd64", "py3.12-macos-arm64"}
+
+	tests := []struct {
+		line    string
+		targets []string
+		// the diagnostic expected, if any
+		code     utils.Code
+		severity utils.Severity
+	}{
+		{line: "requests", targets: all},
+		{line: `tomli; python_version < "3.11"`, targets: []string{"py3.8-linux-x86_64"}, code: utils.CodeNotApplicable, severity: utils.SeverityInfo},
+		{line: `numpy; python_full_version >= "3.9"`, targets: all[1:], code: utils.CodeNotApplicable, severity: utils.SeverityInfo},
+		{line: `pywin32; sys_platform == "win32"`, targets: []string{"py3.12-windows-amd64"}, code: utils.CodeNotApplicable, severity: utils.SeverityInfo},
+		{line: `uvloop; platform_system != "Windows" and python_version >= "3.12"`, targets: []string{"py3.12-linux-x86_64", "py3.12-macos-arm64"}, code: utils.CodeNotApplicable, severity: utils.SeverityInfo},
+		{line: `appnope; platform_machine == "arm64" or os_name == "nt"`, targets: all[2:], code: utils.CodeNotApplicable, severity: utils.SeverityInfo},
+		{line: `legacy; python_version < "3"`, targets: []string{}, code: utils.CodeNotApplicable, severity: utils.SeverityInfo},
+		// markers that can't be evaluated keep the package everywhere
+		{line: `odd; python_version > "abc"`, targets: all, code: utils.CodeInvalidMarker, severity: utils.SeverityWarning},
+	}
+	for _, test := range tests {
+		reqFile, parseDiags := ParseFile("requirements.txt", []byte(test.line+"\n"), nil, nil)
+		if len(parseDiags) > 0 || len(reqFile.Requirements) != 1 {
+			t.Fatalf("ParseFile(%q) = %v, %v", test.line, reqFile.Requirements, parseDiags)
+		}
+		pkg := reqFile.Requirements[0]
+		diags := []utils.Diagnostic{}
+		matchTargets(&pkg, targets, &diags)
+
+		if !slices.Equal(pkg.Targets, test.targets) {
+			t.Errorf("%s: targets = %v, want %v", test.line, pkg.Targets, test.targets)
+		}
+		if test.code == (utils.Code{}) {
+			if len(diags) != 0 {
+				t.Errorf("%s: diagnostics = %v, want none", test.line, diags)
+			}
+			continue
+		}
+		if len(diags) != 1 || diags[0].Code != test.code || diags[0].Severity != test.severity {
+			t.Errorf("%s: diagnostics = %v, want one %v %v", test.line, diags, test.severity, test.code)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package input
+
+import (
+	"sync"
+
+	"github.com/DerekCorniello/pip-req-valid/typosquat"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+// only read the bundled list once, it doesn't change
+var bundledPopular = sync.OnceValue(typosquat.Bundled)
+
+// checkTyposquat reports names that look like a typo of a popular
+// package. That's an error even when the name exists on the index,
+// since that is exactly what a typosquat relies on. Real packages that
+// happen to look like popular ones belong in typosquat/allowed.txt.
+func checkTyposquat(pkg utils.Package, popular *typosquat.List, diags *[]utils.Diagnostic) bool {
+	// a url decides what gets installed, not the name
+	if pkg.Requirement == nil || pkg.Requirement.URL != "" {
+		return true
+	}
+	match, found := popular.Check(pkg.Name)
+	if !found {
+		return true
+	}
+
+	namePos := pkg.Position
+	namePos.EndColumn = namePos.Column + len(pkg.Requirement.Name)
+	var diag utils.Diagnostic
+	switch match.Reason {
+	case typosquat.Separator:
+		diag = utils.NewDiagnostic(namePos, utils.SeverityError, utils.CodePossibleTyposquat,
+			"Package '%s' only differs from the popular package '%s' in its separators, did you mean '%s'?", pkg.Name, match.Name, match.Name)
+	case typosquat.Homoglyph:
+		diag = utils.NewDiagnostic(namePos, utils.SeverityError, utils.CodePossibleTyposquat,
+			"Package '%s' looks like the popular package '%s' with lookalike characters swapped in, did you mean '%s'?", pkg.Name, match.Name, match.Name)
+	default:
+		letters := "letters"
+		if match.Distance == 1 {
+			letters = "letter"
+		}
+		diag = utils.NewDiagnostic(namePos, utils.SeverityError, utils.CodePossibleTyposquat,
+			"Package '%s' is %d %s off from the popular package '%s', did you mean '%s'?", pkg.Name, match.Distance, letters, match.Name, match.Name)
+	}
+	diag.Fix = match.Name
+	*diags = append(*diags, diag)
+	return false
+}
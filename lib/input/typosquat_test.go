@@ -0,0 +1,42 @@
+package input
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	"github.com/DerekCorniello/pip-req-valid/typosquat"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+func TestTyposquatOnIndex(t *testing.T) {
+	project := func(name string) *index.Project {
+		return &index.Project{Name: name, Releases: map[string]*index.ProjectRelease{"1.0": {}}}
+	}
+	// the lookalike is published, that's what a typosquat relies on
+	mem := index.Memory{
+		"requests": project("requests"),
+		"reqeusts": project("reqeusts"),
+	}
+	config := VerifyConfig{Index: mem, PopularNames: typosquat.New([]string{"requests"})}
+
+	reqFile, _ := ParseFile("requirements.txt", []byte("requests\nreqeusts\n"), nil, nil)
+	verified, invalid, diags := VerifyPackages(context.Background(), reqFile.Requirements, reqFile.Options, config)
+
+	if len(verified) != 1 || verified[0].Name != "requests" {
+		t.Errorf("verified = %v, want just requests", verified)
+	}
+	if len(invalid) != 1 || invalid[0].Name != "reqeusts" {
+		t.Errorf("invalid = %v, want the lookalike", invalid)
+	}
+	if len(diags) != 1 {
+		t.Fatalf("diagnostics = %v, want one", diags)
+	}
+	diag := diags[0]
+	if diag.Code != utils.CodePossibleTyposquat || diag.Severity != utils.SeverityError || diag.Fix != "requests" {
+		t.Errorf("diagnostic = %+v, want an RQ026 error suggesting requests", diag)
+	}
+	if diag.Line != 2 || diag.Column != 1 || diag.EndColumn != 9 {
+		t.Errorf("diagnostic at %d:%d-%d, want the name on line 2", diag.Line, diag.Column, diag.EndColumn)
+	}
+}
@@ -1,88 +1,226 @@
 package input
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"slices"
 	"strings"
 
-	"github.com/Masterminds/semver/v3"
-
+	"github.com/DerekCorniello/pip-req-valid/index"
+	"github.com/DerekCorniello/pip-req-valid/pep440"
 	utils "github.com/DerekCorniello/pip-req-valid/utils"
 )
 
-func parseVersionSpecifier(spec string) (string, string, error) {
-	operators := []string{"==", ">=", "<=", ">", "<", "~=", "!="}
+// parseVersions turns the index's version strings into PEP 440
+// versions, anything that doesn't parse is a legacy version pip would
+// skip too. Yanked versions are only kept if withYanked is set.
+func parseVersions(versions []index.Version, withYanked bool) []*pep440.Version {
+	parsed := []*pep440.Version{}
+	for _, version := range versions {
+		if version.Yanked && !withYanked {
+			continue
+		}
+		v, err := pep440.Parse(version.Version)
+		if err != nil {
+			continue
+		}
+		parsed = append(parsed, v)
+	}
+	return parsed
+}
 
-	for _, op := range operators {
-		if strings.HasPrefix(spec, op) {
-			return op, strings.TrimSpace(strings.TrimPrefix(spec, op)), nil
+// yankedVersion gives the index's entry for a version if it's yanked.
+func yankedVersion(versions []index.Version, v *pep440.Version) (index.Version, bool) {
+	for _, version := range versions {
+		if version.Version == v.Original() && version.Yanked {
+			return version, true
 		}
 	}
+	return index.Version{}, false
+}
+
+func allYanked(versions []index.Version) bool {
+	for _, version := range versions {
+		if !version.Yanked {
+			return false
+		}
+	}
+	return len(versions) > 0
+}
 
-	return "", "", fmt.Errorf("invalid version specifier: %s", spec)
+// installCandidate is the version pip would install for the package,
+// the newest one its specifiers allow, leaving out yanked ones unless
+// it's pinned. Also gives back everything the index lists.
+func installCandidate(ctx context.Context, pkg utils.Package, opts GlobalOptions, lookup *versionLookup) ([]index.Version, *pep440.Version, bool) {
+	versions, allowed, ok := installCandidates(ctx, pkg, opts, lookup)
+	if !ok {
+		return nil, nil, false
+	}
+	return versions, allowed[len(allowed)-1], true
 }
 
-func versionMatchesConstraint(version, operator, constraint string, details *[]string) bool {
-	parsedVersion, err := semver.NewVersion(version)
+// installCandidates is every version pip could pick for the package,
+// oldest first, for the checks that look past the newest one.
+func installCandidates(ctx context.Context, pkg utils.Package, opts GlobalOptions, lookup *versionLookup) ([]index.Version, []*pep440.Version, bool) {
+	// urls and local refs have no versions to pick from
+	if pkg.Requirement == nil || pkg.Requirement.URL != "" {
+		return nil, nil, false
+	}
+	versions, err := lookup.get(ctx, pkg)
 	if err != nil {
-		*details = append(*details, fmt.Sprintf("Invalid version format: %s\n", version))
-		return false
+		return nil, nil, false
+	}
+	allowed := allowedVersions(pkg, versions, opts)
+	if len(allowed) == 0 {
+		return nil, nil, false
 	}
+	return versions, allowed, true
+}
 
-	parsedConstraint, err := semver.NewConstraint(fmt.Sprintf("%s %s", operator, constraint))
+// allowedVersions is every version in the list the package's specifiers
+// allow, oldest first, with the same yanked and pre-release rules as
+// installCandidate.
+func allowedVersions(pkg utils.Package, versions []index.Version, opts GlobalOptions) []*pep440.Version {
+	specs, err := pep440.ParseSpecifierSet(strings.Join(pkg.Requirement.SpecifierStrings(), ","))
 	if err != nil {
-		*details = append(*details, fmt.Sprintf("Invalid version constraint: %s %s\n", operator, constraint))
-		return false
+		return nil
 	}
+	_, pinned := pinnedVersion(pkg)
+	allowed := specs.Filter(parseVersions(versions, pinned), opts.Pre)
+	pep440.Sort(allowed)
+	return allowed
+}
 
-	return parsedConstraint.Check(parsedVersion)
+// VerifyPackage checks a single package, see VerifyPackages for
+// checking a whole file.
+func VerifyPackage(ctx context.Context, pkg utils.Package, opts GlobalOptions, diags *[]utils.Diagnostic) bool {
+	return verifyPackage(ctx, pkg, opts, newVersionLookup(VerifyConfig{}, opts), diags)
 }
 
-func VerifyPackage(pkg utils.Package, details *[]string) bool {
+func verifyPackage(ctx context.Context, pkg utils.Package, opts GlobalOptions, lookup *versionLookup, diags *[]utils.Diagnostic) bool {
 
-	versions, err := utils.GetAllowedPackageVersions(&pkg, details)
-	if err != nil {
-		*details = append(*details, fmt.Sprintf("An error occurred while retrieving allowed package versions: %v\n", err))
+	// local refs and option lines were already reported while parsing
+	if slices.Contains(pkg.VersionSpecs, "local") {
 		return false
 	}
 
-	if slices.Contains(pkg.VersionSpecs, "latest") {
+	versions, err := lookup.get(ctx, pkg)
+	if errors.Is(err, utils.ErrUnverifiable) {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityInfo, utils.CodeUnverifiable,
+			"Cannot verify %v", err))
+		return false
+	} else if errors.Is(err, index.ErrNotFound) {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeUnknownPackage,
+			"Package '%s' was not found on the index.", pkg.Name))
+		return false
+	} else if err != nil {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeIndexError,
+			"An error occurred while retrieving allowed package versions: %v", err))
+		return false
+	}
+	// the other indexes answered, but what the failed ones have is missing
+	for _, failure := range lookup.failed(pkg) {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityWarning, utils.CodeIndexError,
+			"Could not check every index for '%s', going by the ones that answered: %v", pkg.Name, failure))
+	}
 
+	if slices.Contains(pkg.VersionSpecs, "latest") {
+		if allYanked(versions) {
+			*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeYankedRelease,
+				"Every version of package '%s' has been yanked, pin one exactly to install it anyway.", pkg.Name))
+			return false
+		}
 		return len(versions) > 0
 	}
 
-	for _, spec := range pkg.VersionSpecs {
-		if strings.HasPrefix(spec, "==") {
-			targetVersion := strings.TrimPrefix(spec, "==")
-			if slices.Contains(versions, targetVersion) {
-				return true
-			} else {
-				*details = append(*details, fmt.Sprintf("Specified version '%s' not found for package '%s'.\n", targetVersion, pkg.Name))
-				return false
+	specs, err := pep440.ParseSpecifierSet(strings.Join(pkg.VersionSpecs, ","))
+	if err != nil {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeInvalidSpecifier,
+			"Error parsing version specifier for '%s': %v", pkg.Name, err))
+		return false
+	}
+
+	// like pip, an exact pin still gets a yanked release, with a
+	// warning, anything else skips them
+	if _, pinned := pinnedVersion(pkg); pinned {
+		matched := specs.Filter(parseVersions(versions, true), opts.Pre)
+		for _, v := range matched {
+			if yanked, ok := yankedVersion(versions, v); ok {
+				reason := yanked.YankedReason
+				if reason == "" {
+					reason = "no reason given"
+				}
+				*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityWarning, utils.CodeYankedRelease,
+					"Version %s of package '%s' has been yanked: %s.", v.Original(), pkg.Name, reason))
 			}
 		}
+		if len(matched) > 0 {
+			return true
+		}
+	} else if len(specs.Filter(parseVersions(versions, false), opts.Pre)) > 0 {
+		return true
+	} else if len(specs) == 0 && allYanked(versions) {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeYankedRelease,
+			"Every version of package '%s' has been yanked, pin one exactly to install it anyway.", pkg.Name))
+		return false
+	} else if len(specs.Filter(parseVersions(versions, true), opts.Pre)) > 0 {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeYankedRelease,
+			"Only yanked versions of package '%s' satisfy '%s', pin one exactly to install it anyway.", pkg.Name, specs))
+		return false
 	}
 
-	for _, version := range versions {
-		versionValid := true
-		for _, spec := range pkg.VersionSpecs {
-			op, targetVersion, parseErr := parseVersionSpecifier(spec)
-			if parseErr != nil {
-				*details = append(*details, fmt.Sprintf("Error parsing version specifier '%s': %v\n", spec, parseErr))
-				versionValid = false
-				break
+	// pins get a more specific message since it is the common case, and
+	// the nearest release as a fix
+	if len(specs) == 1 && specs[0].Op == "==" {
+		pos := pkg.Position
+		if pkg.PinPosition.Line != 0 {
+			pos = pkg.PinPosition
+		}
+		diag := utils.NewDiagnostic(pos, utils.SeverityError, utils.CodeUnsatisfiableSpecifier,
+			"Specified version '%s' not found for package '%s'.", specs[0].Version, pkg.Name)
+		if pin, err := pep440.Parse(specs[0].Version); err == nil && !strings.HasSuffix(specs[0].Version, ".*") {
+			if closest := closestVersion(pin, parseVersions(versions, false), opts.Pre || pin.IsPrerelease()); closest != nil {
+				diag.Fix = closest.Original()
 			}
+		}
+		*diags = append(*diags, diag)
+	} else {
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeUnsatisfiableSpecifier,
+			"No version of package '%s' satisfies '%s'.", pkg.Name, specs))
+	}
+	return false
+}
 
-			if !versionMatchesConstraint(version, op, targetVersion, details) {
-				versionValid = false
-				break
+// closestVersion is the release nearest to a pin that doesn't exist:
+// of the ones sharing the most leading release segments with it, the
+// newest one below it, or the oldest above it when there's none.
+func closestVersion(pin *pep440.Version, versions []*pep440.Version, allowPre bool) *pep440.Version {
+	best, bestShared := []*pep440.Version{}, -1
+	for _, v := range versions {
+		if v.IsPrerelease() && !allowPre {
+			continue
+		}
+		shared := 0
+		if v.Epoch == pin.Epoch {
+			for shared < len(v.Release) && shared < len(pin.Release) && v.Release[shared] == pin.Release[shared] {
+				shared++
 			}
 		}
-
-		if versionValid {
-			return true
+		switch {
+		case shared > bestShared:
+			best, bestShared = []*pep440.Version{v}, shared
+		case shared == bestShared:
+			best = append(best, v)
 		}
 	}
-
-	return false
+	if len(best) == 0 {
+		return nil
+	}
+	pep440.Sort(best)
+	for i := len(best) - 1; i >= 0; i-- {
+		if best[i].LessThan(pin) {
+			return best[i]
+		}
+	}
+	return best[0]
 }
@@ -0,0 +1,284 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	"github.com/DerekCorniello/pip-req-valid/license"
+	"github.com/DerekCorniello/pip-req-valid/typosquat"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+	"github.com/DerekCorniello/pip-req-valid/vuln"
+)
+
+const (
+	DefaultWorkers        = 8
+	DefaultRequestTimeout = 15 * time.Second
+)
+
+// VerifyConfig tunes how VerifyPackages talks to the indexes. The zero
+// value uses the defaults.
+type VerifyConfig struct {
+	// how many lookups can be in flight at once
+	Workers int
+	// how long a single package lookup gets before giving up
+	RequestTimeout time.Duration
+	// where packages get looked up, nil builds one from the index
+	// options in the file
+	Index index.PackageIndex
+	// credentials and caching for the indexes named in the file
+	IndexConfig index.Config
+	// the environments the file gets installed into, markers are
+	// evaluated against each one. Empty means just DefaultTarget.
+	Targets []Target
+	// interpreter versions, like "3.12", to check each package's
+	// Requires-Python against. Empty skips the check.
+	Pythons []string
+	// advisories to check the installed versions against, nil skips
+	// the check
+	Vulnerabilities *vuln.DB
+	// licenses are looked up and checked against it when set, an empty
+	// policy just reports them
+	Licenses *license.Policy
+	// popular package names to catch typos of, nil uses the bundled
+	// list
+	PopularNames *typosquat.List
+	// internal names to check for dependency confusion, nil skips the
+	// check
+	Confusion *ConfusionGuard
+	// thresholds for the health signals, nil skips them
+	Health *HealthConfig
+}
+
+func (config VerifyConfig) workers() int {
+	if config.Workers <= 0 {
+		return DefaultWorkers
+	}
+	return config.Workers
+}
+
+func (config VerifyConfig) packageIndex(opts GlobalOptions) index.PackageIndex {
+	if config.Index != nil {
+		return config.Index
+	}
+	return index.ForURLs(opts.IndexURLs(), opts.indexConfig(config.IndexConfig))
+}
+
+func (config VerifyConfig) popularNames() *typosquat.List {
+	if config.PopularNames == nil {
+		return bundledPopular()
+	}
+	return config.PopularNames
+}
+
+func (config VerifyConfig) targets() []Target {
+	if len(config.Targets) == 0 {
+		return []Target{TargetProfiles[DefaultTarget]}
+	}
+	return config.Targets
+}
+
+func (config VerifyConfig) requestTimeout() time.Duration {
+	if config.RequestTimeout <= 0 {
+		return DefaultRequestTimeout
+	}
+	return config.RequestTimeout
+}
+
+// versionLookup makes sure each package is only looked up once per
+// run, no matter how many lines ask for it.
+type versionLookup struct {
+	config  VerifyConfig
+	index   index.PackageIndex
+	mu      sync.Mutex
+	results map[string]*lookupResult
+}
+
+type lookupResult struct {
+	once     sync.Once
+	versions []index.Version
+	// indexes that failed while others answered, versions has what the
+	// rest had
+	failed []error
+	err    error
+}
+
+func newVersionLookup(config VerifyConfig, opts GlobalOptions) *versionLookup {
+	return &versionLookup{
+		config:  config,
+		index:   config.packageIndex(opts),
+		results: map[string]*lookupResult{},
+	}
+}
+
+// files lists the files of a pinned version of the package. Nothing
+// else asks for the same files, so there's no need to share these.
+func (l *versionLookup) files(ctx context.Context, pkg utils.Package, version string) ([]index.File, error) {
+	versions, err := l.get(ctx, pkg)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, l.config.requestTimeout())
+	defer cancel()
+	return l.index.Files(ctx, pkg.CanonicalName(), indexVersion(versions, version))
+}
+
+// release gets the metadata of one version of the package.
+func (l *versionLookup) release(ctx context.Context, pkg utils.Package, version string) (*index.Release, error) {
+	versions, err := l.get(ctx, pkg)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, l.config.requestTimeout())
+	defer cancel()
+	return l.index.Release(ctx, pkg.CanonicalName(), indexVersion(versions, version))
+}
+
+func (l *versionLookup) get(ctx context.Context, pkg utils.Package) ([]index.Version, error) {
+	key := pkg.Location()
+	l.mu.Lock()
+	result, ok := l.results[key]
+	if !ok {
+		result = &lookupResult{}
+		l.results[key] = result
+	}
+	l.mu.Unlock()
+
+	result.once.Do(func() {
+		ctx, cancel := context.WithTimeout(ctx, l.config.requestTimeout())
+		defer cancel()
+		result.versions, result.err = utils.GetAllowedPackageVersions(ctx, &pkg, l.index)
+		var partial *index.PartialError
+		if errors.As(result.err, &partial) {
+			result.failed, result.err = partial.Errs, nil
+		}
+	})
+	return result.versions, result.err
+}
+
+// failed gives the errors of the indexes that couldn't be asked about
+// the package while others could. Only meaningful after get.
+func (l *versionLookup) failed(pkg utils.Package) []error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if result, ok := l.results[pkg.Location()]; ok {
+		return result.failed
+	}
+	return nil
+}
+
+// VerifyPackages checks every package against the index, running up to
+// config.Workers lookups at a time. Results keep the input order no
+// matter which lookup finishes first. If ctx is cancelled, packages not
+// checked yet come back invalid with a diagnostic saying so.
+//
+// Each package comes back with Targets set to the config.Targets its
+// marker holds in. Packages that apply to none of them are skipped and
+// left out of both lists.
+func VerifyPackages(ctx context.Context, packages []utils.Package, opts GlobalOptions, config VerifyConfig) ([]utils.Package, []utils.Package, []utils.Diagnostic) {
+	return verifyPackages(ctx, packages, opts, config, newVersionLookup(config, opts))
+}
+
+// verifyPackages is VerifyPackages with the lookup passed in, so callers
+// that keep going after verification reuse what it already fetched.
+func verifyPackages(ctx context.Context, packages []utils.Package, opts GlobalOptions, config VerifyConfig, lookup *versionLookup) ([]utils.Package, []utils.Package, []utils.Diagnostic) {
+	type result struct {
+		ok    bool
+		diags []utils.Diagnostic
+	}
+	results := make([]result, len(packages))
+
+	hashMode := hashCheckingMode(packages, opts)
+	var public index.PackageIndex
+	if config.Confusion != nil {
+		public = config.Confusion.Public
+		if public == nil {
+			public = index.ForURL(config.Confusion.publicURL(), config.IndexConfig)
+		}
+	}
+	packages = slices.Clone(packages)
+	for i := range packages {
+		matchTargets(&packages[i], config.targets(), &results[i].diags)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < config.workers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if len(packages[i].Targets) == 0 {
+					continue
+				}
+				// the name checks go first, they explain the errors that
+				// follow
+				safeName := checkTyposquat(packages[i], config.popularNames(), &results[i].diags)
+				if config.Confusion != nil {
+					safeName = checkConfusion(ctx, packages[i], opts, config, public, &results[i].diags) && safeName
+				}
+				results[i].ok = verifyPackage(ctx, packages[i], opts, lookup, &results[i].diags)
+				if results[i].ok && hashMode {
+					results[i].ok = verifyHashes(ctx, packages[i], lookup, &results[i].diags)
+				}
+				if results[i].ok && len(config.Pythons) > 0 {
+					packages[i].Pythons, results[i].ok = checkPythons(ctx, packages[i], opts, lookup, config.Pythons, &results[i].diags)
+				}
+				if results[i].ok {
+					packages[i].SourceBuilds, results[i].ok = checkWheels(ctx, packages[i], opts, lookup, config.targets(), &results[i].diags)
+				}
+				// a vulnerable package still installs, so it stays valid
+				if results[i].ok && config.Vulnerabilities != nil {
+					packages[i].Vulnerabilities = scanVulnerabilities(ctx, packages[i], opts, lookup, config.Vulnerabilities, &results[i].diags)
+				}
+				if results[i].ok && config.Licenses != nil {
+					packages[i].License, results[i].ok = checkLicense(ctx, packages[i], opts, lookup, config.Licenses, &results[i].diags)
+				}
+				if results[i].ok && config.Health != nil {
+					packages[i].Health = checkHealth(ctx, packages[i], opts, lookup, config.Health, &results[i].diags)
+				}
+				// a suspicious name still gets the rest of the checks,
+				// pip would install it after all
+				results[i].ok = results[i].ok && safeName
+			}
+		}()
+	}
+
+	queued := 0
+queue:
+	for queued < len(packages) {
+		select {
+		case jobs <- queued:
+			queued++
+		case <-ctx.Done():
+			break queue
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := queued; i < len(packages); i++ {
+		if len(packages[i].Targets) == 0 {
+			continue
+		}
+		results[i].diags = append(results[i].diags, utils.NewDiagnostic(packages[i].Position, utils.SeverityError,
+			utils.CodeIndexError, "Verification of '%s' was cancelled: %v", packages[i].Name, ctx.Err()))
+	}
+
+	var verifiedPackages, invalidPackages []utils.Package
+	diags := []utils.Diagnostic{}
+	for i, pkg := range packages {
+		if len(pkg.Targets) == 0 {
+			// not installed anywhere we're checking
+		} else if results[i].ok {
+			verifiedPackages = append(verifiedPackages, pkg)
+		} else {
+			invalidPackages = append(invalidPackages, pkg)
+		}
+		diags = append(diags, results[i].diags...)
+	}
+	return verifiedPackages, invalidPackages, diags
+}
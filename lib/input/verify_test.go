@@ -0,0 +1,229 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+// testIndex is the local test index with every version lookup counted.
+// Lookups of names in slow take that long, and while block is open
+// every lookup waits on it.
+type testIndex struct {
+	index.PackageIndex
+	slow  map[string]time.Duration
+	block chan struct{}
+
+	mu      sync.Mutex
+	lookups map[string]int
+	waiting chan struct{}
+}
+
+func newTestIndex(t *testing.T) *testIndex {
+	mem, err := index.LoadDir("../tests/index")
+	if err != nil {
+		t.Fatalf("Failed to load test index: %v", err)
+	}
+	return &testIndex{PackageIndex: mem, lookups: map[string]int{}, waiting: make(chan struct{}, 100)}
+}
+
+func (idx *testIndex) Versions(ctx context.Context, name string) ([]index.Version, error) {
+	idx.mu.Lock()
+	idx.lookups[name]++
+	idx.mu.Unlock()
+	if idx.block != nil {
+		idx.waiting <- struct{}{}
+		select {
+		case <-idx.block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	time.Sleep(idx.slow[name])
+	return idx.PackageIndex.Versions(ctx, name)
+}
+
+func packageNames(pkgs []utils.Package) []string {
+	names := []string{}
+	for _, pkg := range pkgs {
+		names = append(names, pkg.Name)
+	}
+	return names
+}
+
+func TestVerifyPackagesKeepsOrder(t *testing.T) {
+	idx := newTestIndex(t)
+	// the first packages finish last
+	idx.slow = map[string]time.Duration{"numpy": 30 * time.Millisecond, "requests": 20 * time.Millisecond, "flask": 10 * time.Millisecond}
+	content := "numpy\nrequests\nflask\npandas\nmissing-package\nblack\nmypy\npytest\ncryptography\n"
+
+	reqFile, _ := ParseFile("requirements.txt", []byte(content), nil, nil)
+	verified, invalid, diags := VerifyPackages(context.Background(), reqFile.Requirements, reqFile.Options, VerifyConfig{Index: idx, Workers: 3})
+
+	want := []string{"numpy", "requests", "flask", "pandas", "black", "mypy", "pytest", "cryptography"}
+	if got := packageNames(verified); !slices.Equal(got, want) {
+		t.Errorf("verified = %v, want %v", got, want)
+	}
+	if got := packageNames(invalid); !slices.Equal(got, []string{"missing-package"}) {
+		t.Errorf("invalid = %v, want missing-package", got)
+	}
+	if len(diags) != 1 || diags[0].Code != utils.CodeUnknownPackage || diags[0].Line != 5 {
+		t.Errorf("diagnostics = %v, want RQ001 on line 5", diags)
+	}
+}
+
+func TestVerifyPackagesLooksUpOnce(t *testing.T) {
+	idx := newTestIndex(t)
+	content := "requests\nflask\nrequests>=2.28\nRequests[socks]\nflask==2.2.2\n"
+
+	reqFile, _ := ParseFile("requirements.txt", []byte(content), nil, nil)
+	verified, invalid, _ := VerifyPackages(context.Background(), reqFile.Requirements, reqFile.Options, VerifyConfig{Index: idx, Workers: 4})
+
+	// every line is still checked on its own
+	if len(verified) != 5 || len(invalid) != 0 {
+		t.Errorf("verified %v and invalid %v, want all five lines verified", packageNames(verified), packageNames(invalid))
+	}
+	for name, n := range idx.lookups {
+		if n != 1 {
+			t.Errorf("%s was looked up %d times, want once", name, n)
+		}
+	}
+	if len(idx.lookups) != 2 {
+		t.Errorf("lookups = %v, want requests and flask", idx.lookups)
+	}
+}
+
+func TestVerifyPackagesCancel(t *testing.T) {
+	idx := newTestIndex(t)
+	idx.block = make(chan struct{})
+	defer close(idx.block)
+	content := "numpy\nrequests\nflask\npandas\nblack\nmypy\n"
+	reqFile, _ := ParseFile("requirements.txt", []byte(content), nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	type result struct {
+		verified, invalid []utils.Package
+		diags             []utils.Diagnostic
+	}
+	done := make(chan result)
+	go func() {
+		verified, invalid, diags := VerifyPackages(ctx, reqFile.Requirements, reqFile.Options, VerifyConfig{Index: idx, Workers: 2})
+		done <- result{verified, invalid, diags}
+	}()
+
+	// both workers are stuck on a lookup
+	<-idx.waiting
+	<-idx.waiting
+	cancel()
+
+	var got result
+	select {
+	case got = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("VerifyPackages didn't return after the context was cancelled")
+	}
+	if len(got.verified) != 0 || len(got.invalid) != 6 {
+		t.Errorf("verified %v and invalid %v, want every package invalid", packageNames(got.verified), packageNames(got.invalid))
+	}
+	// each package says why, whether it was waiting on the index or never
+	// got picked up
+	lines := []int{}
+	for _, diag := range got.diags {
+		if diag.Severity != utils.SeverityError || !strings.Contains(diag.Message, "cancel") {
+			t.Errorf("unexpected diagnostic %v", diag)
+		}
+		lines = append(lines, diag.Line)
+	}
+	slices.Sort(lines)
+	if !slices.Equal(lines, []int{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("diagnostics on lines %v, want one for every package", lines)
+	}
+}
+
+func TestVerifyPackagesYanked(t *testing.T) {
+	idx := newTestIndex(t)
+	// requests 2.32.0 is yanked in the test index
+	tests := []struct {
+		line     string
+		ok       bool
+		reported bool
+		severity utils.Severity
+	}{
+		// pip still installs an exact pin, with a warning
+		{line: "requests==2.32.0", ok: true, reported: true, severity: utils.SeverityWarning},
+		// a range skips it for 2.31.0
+		{line: "requests>=2.31", ok: true},
+		{line: "requests", ok: true},
+		// and fails when nothing else is left
+		{line: "requests>2.31", reported: true, severity: utils.SeverityError},
+	}
+	for _, test := range tests {
+		reqFile, _ := ParseFile("requirements.txt", []byte(test.line+"\n"), nil, nil)
+		verified, _, diags := VerifyPackages(context.Background(), reqFile.Requirements, reqFile.Options, VerifyConfig{Index: idx})
+
+		if ok := len(verified) == 1; ok != test.ok {
+			t.Errorf("%s: verified = %v, want %v", test.line, ok, test.ok)
+		}
+		if !test.reported {
+			if len(diags) != 0 {
+				t.Errorf("%s: diagnostics = %v, want none", test.line, diags)
+			}
+			continue
+		}
+		if len(diags) != 1 || diags[0].Code != utils.CodeYankedRelease || diags[0].Severity != test.severity {
+			t.Errorf("%s: diagnostics = %v, want one RQ019 %v", test.line, diags, test.severity)
+		}
+	}
+	pinned, _ := ParseFile("requirements.txt", []byte("requests==2.32.0\n"), nil, nil)
+	_, _, diags := VerifyPackages(context.Background(), pinned.Requirements, pinned.Options, VerifyConfig{Index: idx})
+	if len(diags) != 1 || !strings.Contains(diags[0].Message, "CVE-2024-35195") {
+		t.Errorf("diagnostics = %v, want the yank reason", diags)
+	}
+}
+
+// downIndex fails every lookup like an unreachable mirror.
+type downIndex struct {
+	index.PackageIndex
+}
+
+func (downIndex) Versions(ctx context.Context, name string) ([]index.Version, error) {
+	return nil, errors.New("unexpected status 503 from https://mirror.example.com/simple/")
+}
+
+func TestVerifyPackagesIndexDown(t *testing.T) {
+	idx := newTestIndex(t)
+	reqFile, _ := ParseFile("requirements.txt", []byte("requests==2.31.0\n"), nil, nil)
+	verified, _, diags := VerifyPackages(context.Background(), reqFile.Requirements, reqFile.Options, VerifyConfig{Index: index.Multi{idx, downIndex{}}})
+
+	// going by the index that answered
+	if len(verified) != 1 {
+		t.Errorf("verified = %v, want requests", packageNames(verified))
+	}
+	if len(diags) != 1 || diags[0].Code != utils.CodeIndexError || diags[0].Severity != utils.SeverityWarning || !strings.Contains(diags[0].Message, "503") {
+		t.Errorf("diagnostics = %v, want an RQ009 warning about the mirror", diags)
+	}
+}
+
+func TestVerifyPackagesContinuedPin(t *testing.T) {
+	mem := index.Memory{"demo": {Name: "demo", Releases: map[string]*index.ProjectRelease{"1.0": {}}}}
+	content := "demo \\\n  ==1.1 \\\n  --hash=sha256:abc\n"
+	reqFile, _ := ParseFile("requirements.txt", []byte(content), nil, nil)
+	_, _, diags := VerifyPackages(context.Background(), reqFile.Requirements, reqFile.Options, VerifyConfig{Index: mem})
+
+	// the pin is on the second line of the requirement
+	want := utils.Position{File: "requirements.txt", Line: 2, Column: 5, EndColumn: 8}
+	if len(diags) != 1 || diags[0].Code != utils.CodeUnsatisfiableSpecifier || diags[0].Position != want {
+		t.Fatalf("diagnostics = %v, want one RQ002 at %v", diags, want)
+	}
+	doc := NewDocument("requirements.txt", []byte(content))
+	if text, ok := doc.Text(diags[0].Position); !ok || text != "1.1" {
+		t.Errorf("Text(%v) = %q, %v, want the pin", diags[0].Position, text, ok)
+	}
+}
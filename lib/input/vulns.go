@@ -0,0 +1,110 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/pep440"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+	"github.com/DerekCorniello/pip-req-valid/vuln"
+)
+
+// scanVulnerabilities checks the version pip would install against the
+// advisories in db, reporting each one that affects it. For every
+// advisory, and for all of them together, it works out the smallest
+// release on the index that clears it.
+func scanVulnerabilities(ctx context.Context, pkg utils.Package, opts GlobalOptions, lookup *versionLookup, db *vuln.DB, diags *[]utils.Diagnostic) *utils.VulnerabilityScan {
+	if len(db.Package(pkg.Name)) == 0 {
+		return nil
+	}
+	versions, installed, ok := installCandidate(ctx, pkg, opts, lookup)
+	if !ok {
+		return nil
+	}
+
+	advisories := db.Query(pkg.Name, installed)
+	if len(advisories) == 0 {
+		return nil
+	}
+	// worst first, then by id so the output is stable
+	sort.SliceStable(advisories, func(i, j int) bool {
+		a, _ := advisories[i].Rating(pkg.Name)
+		b, _ := advisories[j].Rating(pkg.Name)
+		if vuln.RatingRank(a) != vuln.RatingRank(b) {
+			return vuln.RatingRank(a) > vuln.RatingRank(b)
+		}
+		return advisories[i].ID < advisories[j].ID
+	})
+
+	// the releases that could be upgraded to, oldest first
+	candidates := []*pep440.Version{}
+	for _, v := range parseVersions(versions, false) {
+		if v.GreaterThan(installed) && (!v.IsPrerelease() || opts.Pre || installed.IsPrerelease()) {
+			candidates = append(candidates, v)
+		}
+	}
+	pep440.Sort(candidates)
+
+	scan := &utils.VulnerabilityScan{Version: installed.Original(), Vulnerabilities: []utils.Vulnerability{}}
+	for _, advisory := range advisories {
+		severity, cvss := advisory.Rating(pkg.Name)
+		found := utils.Vulnerability{
+			ID:       advisory.ID,
+			Aliases:  advisory.Aliases,
+			Summary:  advisory.Summary,
+			Severity: severity,
+			CVSS:     cvss,
+			FixedIn:  advisory.FixedIn(pkg.Name),
+		}
+		for _, candidate := range candidates {
+			if !advisory.Affects(pkg.Name, candidate) {
+				found.Upgrade = candidate.Original()
+				break
+			}
+		}
+		scan.Vulnerabilities = append(scan.Vulnerabilities, found)
+
+		msg := fmt.Sprintf("Package '%s==%s' is affected by %s (%s)", pkg.Name, installed.Original(), advisory.ID,
+			strings.Join(append([]string{severity}, advisory.Aliases...), ", "))
+		if advisory.Summary != "" {
+			msg += ": " + strings.TrimSuffix(advisory.Summary, ".")
+		}
+		if found.Upgrade != "" {
+			msg += "; " + found.Upgrade + " is the first release it doesn't affect"
+		} else {
+			msg += "; no release fixes it yet"
+		}
+		*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityWarning, utils.CodeKnownVulnerability, "%s.", msg))
+	}
+
+	for _, candidate := range candidates {
+		if len(db.Query(pkg.Name, candidate)) == 0 {
+			scan.Upgrade = candidate.Original()
+			break
+		}
+	}
+	return scan
+}
+
+// VulnerablePackage is one row of the vulnerability report.
+type VulnerablePackage struct {
+	Package string `json:"package"`
+	utils.Position
+	utils.VulnerabilityScan
+}
+
+// BuildVulnerabilityReport collects what VerifyPackages found for each
+// package, in the order of files. Packages without any findings are
+// left out.
+func BuildVulnerabilityReport(packages []utils.Package, files utils.FileOrder) []VulnerablePackage {
+	report := []VulnerablePackage{}
+	for _, pkg := range packages {
+		if pkg.Vulnerabilities != nil {
+			report = append(report, VulnerablePackage{Package: pkg.Name, Position: pkg.Position, VulnerabilityScan: *pkg.Vulnerabilities})
+		}
+	}
+	utils.SortByPosition(report, files, func(row VulnerablePackage) utils.Position { return row.Position })
+	return report
+}
@@ -0,0 +1,240 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	utils "github.com/DerekCorniello/pip-req-valid/utils"
+)
+
+// wheelTag is one PEP 425 python-abi-platform triple a wheel is built
+// for.
+type wheelTag struct {
+	Python   string
+	ABI      string
+	Platform string
+}
+
+// parseWheelFilename gives the tags of a wheel named like
+// `name-1.0(-build)?-py3-none-any.whl`. Each part can be a compressed
+// set like `py2.py3`, the result has every combination of them.
+func parseWheelFilename(filename string) ([]wheelTag, error) {
+	stem, ok := strings.CutSuffix(filename, ".whl")
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a wheel", filename)
+	}
+	parts := strings.Split(stem, "-")
+	if len(parts) != 5 && len(parts) != 6 {
+		return nil, fmt.Errorf("wheel filename '%s' needs 5 or 6 dash separated parts", filename)
+	}
+	pythons := strings.Split(parts[len(parts)-3], ".")
+	abis := strings.Split(parts[len(parts)-2], ".")
+	plats := strings.Split(parts[len(parts)-1], ".")
+
+	tags := []wheelTag{}
+	for _, python := range pythons {
+		for _, abi := range abis {
+			for _, plat := range plats {
+				tags = append(tags, wheelTag{Python: python, ABI: abi, Platform: plat})
+			}
+		}
+	}
+	return tags, nil
+}
+
+// supportsWheel checks whether CPython on the target would take any of
+// a wheel's tags, following the order of packaging's sys_tags: the
+// interpreter's own abi, the stable abi of it or an older 3.x, and pure
+// python wheels.
+func (t Target) supportsWheel(tags []wheelTag) bool {
+	major, minor, ok := strings.Cut(t.Env["python_version"], ".")
+	if !ok {
+		return false
+	}
+	minorVersion, err := strconv.Atoi(minor)
+	if err != nil {
+		return false
+	}
+	cp := "cp" + major + minor
+
+	for _, tag := range tags {
+		if tag.Platform == "any" {
+			if tag.ABI == "none" && (tag.Python == cp || pythonTagAtMost(tag.Python, "py", major, minorVersion)) {
+				return true
+			}
+			continue
+		}
+		if !slices.Contains(t.Platforms, tag.Platform) {
+			continue
+		}
+		switch tag.ABI {
+		case cp:
+			if tag.Python == cp {
+				return true
+			}
+		case "abi3":
+			if pythonTagAtMost(tag.Python, "cp", major, minorVersion) {
+				return true
+			}
+		case "none":
+			if tag.Python == cp || pythonTagAtMost(tag.Python, "py", major, minorVersion) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pythonTagAtMost matches `py3`, and `py38` style tags up to the
+// target's minor version.
+func pythonTagAtMost(tag, prefix, major string, minor int) bool {
+	version, ok := strings.CutPrefix(tag, prefix+major)
+	if !ok {
+		return false
+	}
+	if version == "" {
+		return prefix == "py"
+	}
+	v, err := strconv.Atoi(version)
+	return err == nil && v <= minor
+}
+
+// checkWheels looks at the files of the version pip would pick on each
+// of the package's targets, and reports the targets where no wheel
+// fits. With an sdist pip builds it there, which needs a compiler for
+// anything with extensions, without one it can't install at all.
+// --no-binary and --only-binary rule out wheels and sdists the same way
+// they do for pip, and --prefer-binary picks the newest version with a
+// wheel for the target over a newer one without.
+// The names of the targets that build from source are returned.
+func checkWheels(ctx context.Context, pkg utils.Package, opts GlobalOptions, lookup *versionLookup, targets []Target, diags *[]utils.Diagnostic) ([]string, bool) {
+	// urls and local refs have no files on the index
+	versions, allowed, ok := installCandidates(ctx, pkg, opts, lookup)
+	if !ok {
+		return nil, true
+	}
+	_, pinned := pinnedVersion(pkg)
+	binary, source := opts.allowedFormats(pkg.Name)
+
+	byVersion := map[string]index.Version{}
+	for _, version := range versions {
+		byVersion[version.Version] = version
+	}
+	slices.Reverse(allowed)
+
+	// grouped by version, the targets can end up on different ones
+	fromSource := map[string][]string{}
+	unavailable := map[string][]string{}
+	order := []string{}
+	for _, target := range targets {
+		if !slices.Contains(pkg.Targets, target.Name) {
+			continue
+		}
+		candidates := []string{}
+		interpreter, err := targetPython(target)
+		if err != nil {
+			// without an interpreter every version is a candidate, like
+			// pip does with a Requires-Python it can't read
+			*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityWarning, utils.CodeUnverifiable,
+				"Cannot check the Requires-Python of '%s' for target '%s': %v.", pkg.Name, target.Name, err))
+		}
+		for _, v := range allowed {
+			if err != nil || supportsPython(byVersion[v.Original()].RequiresPython, interpreter) {
+				candidates = append(candidates, v.Original())
+			}
+		}
+		if len(candidates) == 0 {
+			candidates = []string{allowed[0].Original()}
+		}
+		// only --prefer-binary looks past the newest version
+		if !opts.PreferBinary || !binary {
+			candidates = candidates[:1]
+		}
+
+		version, wheel, sdist, found := candidates[0], false, false, false
+		for _, candidate := range candidates {
+			files, err := lookup.files(ctx, pkg, candidate)
+			if err != nil || len(files) == 0 {
+				continue
+			}
+			hasWheel, hasSdist := distributions(files, target, pinned)
+			if !found {
+				version, wheel, sdist, found = candidate, hasWheel && binary, hasSdist && source, true
+			}
+			if hasWheel && binary {
+				version, wheel = candidate, true
+				break
+			}
+		}
+		if !found {
+			// nothing to go by, hashes and python checks say so already
+			continue
+		}
+		if wheel {
+			continue
+		}
+		if !slices.Contains(order, version) {
+			order = append(order, version)
+		}
+		if sdist {
+			fromSource[version] = append(fromSource[version], target.Name)
+		} else {
+			unavailable[version] = append(unavailable[version], target.Name)
+		}
+	}
+
+	ok = true
+	builds := []string{}
+	for _, version := range order {
+		if names := fromSource[version]; len(names) > 0 {
+			reason := "has no wheel for %s"
+			if !binary {
+				reason = "can't use a wheel on %s because of --no-binary"
+			}
+			*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityWarning, utils.CodeBuildFromSource,
+				"Package '%s==%s' "+reason+", pip will build it from the sdist there, which needs a compiler if it has extensions.",
+				pkg.Name, version, strings.Join(names, ", ")))
+			builds = append(builds, names...)
+		}
+		if names := unavailable[version]; len(names) > 0 {
+			msg := "Package '%s==%s' has no wheel or sdist that installs on %s."
+			if !source {
+				msg = "Package '%s==%s' has no wheel for %s, and --only-binary rules out building the sdist."
+			}
+			*diags = append(*diags, utils.NewDiagnostic(pkg.Position, utils.SeverityError, utils.CodeNoCompatibleDistribution,
+				msg, pkg.Name, version, strings.Join(names, ", ")))
+			ok = false
+		}
+	}
+	return builds, ok
+}
+
+// distributions says whether the files have a wheel that installs on the
+// target and an sdist. Yanked files only count for exact pins.
+func distributions(files []index.File, target Target, pinned bool) (wheel, sdist bool) {
+	for _, file := range files {
+		if file.Yanked && !pinned {
+			continue
+		}
+		tags, err := parseWheelFilename(file.Filename)
+		if err != nil {
+			sdist = sdist || file.PackageType == "sdist" || isSdist(file.Filename)
+			continue
+		}
+		wheel = wheel || target.supportsWheel(tags)
+	}
+	return wheel, sdist
+}
+
+func isSdist(filename string) bool {
+	for _, ext := range []string{".tar.gz", ".zip", ".tar.bz2", ".tar.xz", ".tgz", ".tar"} {
+		if strings.HasSuffix(filename, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package input
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseWheelFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		tags     []wheelTag
+	}{
+		{"requests-2.31.0-py3-none-any.whl", []wheelTag{{"py3", "none", "any"}}},
+		// the build number doesn't change the tags
+		{"numpy-1.26.4-1-cp312-cp312-win_amd64.whl", []wheelTag{{"cp312", "cp312", "win_amd64"}}},
+		{"six-1.16.0-py2.py3-none-any.whl", []wheelTag{{"py2", "none", "any"}, {"py3", "none", "any"}}},
+		{"pkg-1.0-cp38-abi3-manylinux_2_17_x86_64.manylinux2014_x86_64.whl", []wheelTag{
+			{"cp38", "abi3", "manylinux_2_17_x86_64"},
+			{"cp38", "abi3", "manylinux2014_x86_64"},
+		}},
+	}
+	for _, test := range tests {
+		tags, err := parseWheelFilename(test.filename)
+		if err != nil {
+			t.Errorf("parseWheelFilename(%q) error: %v", test.filename, err)
+			continue
+		}
+		if !slices.Equal(tags, test.tags) {
+			t.Errorf("parseWheelFilename(%q) = %v, want %v", test.filename, tags, test.tags)
+		}
+	}
+
+	for _, bad := range []string{"pkg-1.0.tar.gz", "pkg-1.0-py3-none.whl", "pkg-1.0-1-2-py3-none-any.whl"} {
+		if _, err := parseWheelFilename(bad); err == nil {
+			t.Errorf("parseWheelFilename(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSupportsWheel(t *testing.T) {
+	tests := []struct {
+		filename string
+		// the targets that take it
+		targets []string
+	}{
+		{"pkg-1.0-py3-none-any.whl", []string{"py3.8-linux-x86_64", "py3.12-linux-x86_64", "py3.12-alpine-x86_64", "py3.12-macos-arm64", "py3.12-windows-amd64"}},
+		{"pkg-1.0-py2.py3-none-any.whl", []string{"py3.8-linux-x86_64", "py3.12-linux-x86_64", "py3.12-alpine-x86_64", "py3.12-macos-arm64", "py3.12-windows-amd64"}},
+		{"pkg-1.0-py2-none-any.whl", nil},
+		{"pkg-1.0-py310-none-any.whl", []string{"py3.12-linux-x86_64", "py3.12-alpine-x86_64", "py3.12-macos-arm64", "py3.12-windows-amd64"}},
+		// the interpreter's own abi
+		{"pkg-1.0-cp312-cp312-manylinux_2_17_x86_64.manylinux2014_x86_64.whl", []string{"py3.12-linux-x86_64"}},
+		{"pkg-1.0-2-cp312-cp312-manylinux1_x86_64.whl", []string{"py3.12-linux-x86_64"}},
+		// too new a glibc for bookworm
+		{"pkg-1.0-cp312-cp312-manylinux_2_38_x86_64.whl", nil},
+		{"pkg-1.0-cp312-cp312-musllinux_1_1_x86_64.whl", []string{"py3.12-alpine-x86_64"}},
+		{"pkg-1.0-cp312-cp312-musllinux_1_2_aarch64.whl", nil},
+		// the stable abi of any older 3.x
+		{"pkg-1.0-cp39-abi3-manylinux_2_17_x86_64.whl", []string{"py3.12-linux-x86_64"}},
+		{"pkg-1.0-cp39-abi3-macosx_11_0_arm64.whl", []string{"py3.12-macos-arm64"}},
+		{"pkg-1.0-cp313-abi3-win_amd64.whl", nil},
+		{"pkg-1.0-cp38-cp38-win_amd64.whl", nil},
+		{"pkg-1.0-cp38-cp38-manylinux2014_x86_64.whl", []string{"py3.8-linux-x86_64"}},
+	}
+	targets, err := ParseTargets("py3.8-linux-x86_64,py3.12-linux-x86_64,py3.12-alpine-x86_64,py3.12-macos-arm64,py3.12-windows-amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		tags, err := parseWheelFilename(test.filename)
+		if err != nil {
+			t.Fatalf("parseWheelFilename(%q) error: %v", test.filename, err)
+		}
+		got := []string{}
+		for _, target := range targets {
+			if target.supportsWheel(tags) {
+				got = append(got, target.Name)
+			}
+		}
+		if !slices.Equal(got, test.targets) {
+			t.Errorf("%s installs on %v, want %v", test.filename, got, test.targets)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package license
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Policy says which licenses may be shipped. Entries are SPDX ids or
+// globs of them, like `GPL-*`. A license on Deny is never allowed, and
+// when Allow isn't empty only the licenses on it are.
+type Policy struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+	// treat packages whose license can't be worked out as violations
+	// instead of just warning about them
+	DenyUnknown bool `json:"deny_unknown,omitempty"`
+}
+
+// LoadPolicy reads a Policy from a JSON file, like
+// `{"deny": ["GPL-*", "AGPL-*", "LicenseRef-GPL", "LicenseRef-AGPL"]}`.
+func LoadPolicy(file string) (*Policy, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	policy := &Policy{}
+	if err := json.Unmarshal(content, policy); err != nil {
+		return nil, err
+	}
+	for _, pattern := range slices.Concat(policy.Allow, policy.Deny) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.New("invalid pattern '" + pattern + "'")
+		}
+	}
+	return policy, nil
+}
+
+// Allowed checks a single license id against the policy.
+func (p *Policy) Allowed(id string) bool {
+	if p == nil {
+		return true
+	}
+	if matchAny(p.Deny, id) {
+		return false
+	}
+	return len(p.Allow) == 0 || matchAny(p.Allow, id)
+}
+
+func matchAny(patterns []string, id string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(id)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Check evaluates an SPDX expression: an OR needs one allowed side, an
+// AND needs both. It gives back the ids that keep it from being
+// allowed, empty when it is.
+func (p *Policy) Check(expression string) ([]string, error) {
+	node, err := parse(tokenize(expression))
+	if err != nil {
+		return nil, err
+	}
+	if p.allows(node) {
+		return nil, nil
+	}
+	blocked := []string{}
+	for _, id := range node.ids() {
+		if !p.Allowed(id) && !slices.Contains(blocked, id) {
+			blocked = append(blocked, id)
+		}
+	}
+	return blocked, nil
+}
+
+func (p *Policy) allows(n *node) bool {
+	switch n.op {
+	case "OR":
+		return p.allows(n.left) || p.allows(n.right)
+	case "AND":
+		return p.allows(n.left) && p.allows(n.right)
+	}
+	// an exception only ever grants more, the license decides
+	return p.Allowed(n.id)
+}
+
+// node is a parsed SPDX expression, either an operator with two sides
+// or a single license id.
+type node struct {
+	op          string
+	left, right *node
+	id          string
+}
+
+func (n *node) ids() []string {
+	if n.op == "" {
+		return []string{n.id}
+	}
+	return append(n.left.ids(), n.right.ids()...)
+}
+
+var errInvalidExpression = errors.New("invalid SPDX expression")
+
+// parse reads tokens with the SPDX precedence, WITH binds tightest,
+// then AND, then OR.
+func parse(tokens []string) (*node, error) {
+	pos := 0
+	var or, and, atom func() (*node, error)
+	or = func() (*node, error) {
+		left, err := and()
+		if err != nil {
+			return nil, err
+		}
+		for pos < len(tokens) && strings.EqualFold(tokens[pos], "OR") {
+			pos++
+			right, err := and()
+			if err != nil {
+				return nil, err
+			}
+			left = &node{op: "OR", left: left, right: right}
+		}
+		return left, nil
+	}
+	and = func() (*node, error) {
+		left, err := atom()
+		if err != nil {
+			return nil, err
+		}
+		for pos < len(tokens) && strings.EqualFold(tokens[pos], "AND") {
+			pos++
+			right, err := atom()
+			if err != nil {
+				return nil, err
+			}
+			left = &node{op: "AND", left: left, right: right}
+		}
+		return left, nil
+	}
+	atom = func() (*node, error) {
+		if pos >= len(tokens) {
+			return nil, errInvalidExpression
+		}
+		token := tokens[pos]
+		pos++
+		switch {
+		case token == "(":
+			inner, err := or()
+			if err != nil {
+				return nil, err
+			}
+			if pos >= len(tokens) || tokens[pos] != ")" {
+				return nil, errInvalidExpression
+			}
+			pos++
+			return inner, nil
+		case token == ")" || strings.EqualFold(token, "AND") || strings.EqualFold(token, "OR") || strings.EqualFold(token, "WITH"):
+			return nil, errInvalidExpression
+		}
+		if pos < len(tokens) && strings.EqualFold(tokens[pos], "WITH") {
+			if pos+1 >= len(tokens) {
+				return nil, errInvalidExpression
+			}
+			pos += 2
+		}
+		return &node{id: token}, nil
+	}
+
+	n, err := or()
+	if err != nil {
+		return nil, err
+	}
+	if pos != len(tokens) {
+		return nil, errInvalidExpression
+	}
+	return n, nil
+}
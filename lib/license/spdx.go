@@ -0,0 +1,277 @@
+package license
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// the SPDX ids packages on PyPI actually use, by lowercased id
+var spdxIDs = map[string]string{}
+
+func init() {
+	for _, id := range []string{
+		"0BSD", "AFL-3.0", "AGPL-3.0-only", "AGPL-3.0-or-later", "Apache-1.1", "Apache-2.0",
+		"Artistic-2.0", "BSD-2-Clause", "BSD-3-Clause", "BSD-4-Clause", "BSL-1.0", "CC-BY-4.0",
+		"CC-BY-SA-4.0", "CC0-1.0", "CDDL-1.0", "CNRI-Python", "EPL-1.0", "EPL-2.0", "EUPL-1.2",
+		"GPL-2.0-only", "GPL-2.0-or-later", "GPL-3.0-only", "GPL-3.0-or-later", "HPND", "ISC",
+		"LGPL-2.0-only", "LGPL-2.0-or-later", "LGPL-2.1-only", "LGPL-2.1-or-later", "LGPL-3.0-only",
+		"LGPL-3.0-or-later", "MIT", "MIT-0", "MPL-1.1", "MPL-2.0", "OFL-1.1", "PSF-2.0",
+		"Python-2.0", "Unlicense", "UPL-1.0", "WTFPL", "Zlib", "ZPL-2.1",
+	} {
+		spdxIDs[strings.ToLower(id)] = id
+	}
+	// the deprecated GNU ids, still common in older metadata
+	for _, family := range []string{"GPL-2.0", "GPL-3.0", "LGPL-2.0", "LGPL-2.1", "LGPL-3.0", "AGPL-3.0"} {
+		spdxIDs[strings.ToLower(family)] = family + "-only"
+		spdxIDs[strings.ToLower(family)+"+"] = family + "-or-later"
+	}
+}
+
+// what people write in the License field instead of an id, lowercased.
+// Unversioned GNU and BSD mentions can't be pinned down to one license,
+// so they get a LicenseRef for the family.
+var aliases = map[string]string{
+	"mit license":                          "MIT",
+	"the mit license":                      "MIT",
+	"mit/expat":                            "MIT",
+	"expat":                                "MIT",
+	"apache":                               "Apache-2.0",
+	"apache 2":                             "Apache-2.0",
+	"apache 2.0":                           "Apache-2.0",
+	"apache-2":                             "Apache-2.0",
+	"apache license":                       "Apache-2.0",
+	"apache license 2.0":                   "Apache-2.0",
+	"apache license, version 2.0":          "Apache-2.0",
+	"apache license version 2.0":           "Apache-2.0",
+	"apache software license":              "Apache-2.0",
+	"apache software license 2.0":          "Apache-2.0",
+	"asl 2.0":                              "Apache-2.0",
+	"bsd":                                  "LicenseRef-BSD",
+	"bsd license":                          "LicenseRef-BSD",
+	"bsd-like":                             "LicenseRef-BSD",
+	"bsd 3-clause":                         "BSD-3-Clause",
+	"bsd 3-clause license":                 "BSD-3-Clause",
+	"bsd-3":                                "BSD-3-Clause",
+	"3-clause bsd":                         "BSD-3-Clause",
+	"3-clause bsd license":                 "BSD-3-Clause",
+	"new bsd":                              "BSD-3-Clause",
+	"new bsd license":                      "BSD-3-Clause",
+	"modified bsd":                         "BSD-3-Clause",
+	"modified bsd license":                 "BSD-3-Clause",
+	"bsd 2-clause":                         "BSD-2-Clause",
+	"bsd 2-clause license":                 "BSD-2-Clause",
+	"bsd-2":                                "BSD-2-Clause",
+	"2-clause bsd":                         "BSD-2-Clause",
+	"simplified bsd":                       "BSD-2-Clause",
+	"freebsd":                              "BSD-2-Clause",
+	"isc license":                          "ISC",
+	"iscl":                                 "ISC",
+	"psf":                                  "PSF-2.0",
+	"psf license":                          "PSF-2.0",
+	"psfl":                                 "PSF-2.0",
+	"python software foundation license":   "PSF-2.0",
+	"mpl 2.0":                              "MPL-2.0",
+	"mpl-2":                                "MPL-2.0",
+	"mozilla public license 2.0":           "MPL-2.0",
+	"mozilla public license 2.0 (mpl 2.0)": "MPL-2.0",
+	"gpl":                                  "LicenseRef-GPL",
+	"gnu gpl":                              "LicenseRef-GPL",
+	"gplv2":                                "GPL-2.0-only",
+	"gplv2+":                               "GPL-2.0-or-later",
+	"gpl v2":                               "GPL-2.0-only",
+	"gplv3":                                "GPL-3.0-only",
+	"gplv3+":                               "GPL-3.0-or-later",
+	"gpl v3":                               "GPL-3.0-only",
+	"gnu gplv3":                            "GPL-3.0-only",
+	"lgpl":                                 "LicenseRef-LGPL",
+	"lgplv2":                               "LGPL-2.0-only",
+	"lgplv2+":                              "LGPL-2.0-or-later",
+	"lgplv3":                               "LGPL-3.0-only",
+	"lgplv3+":                              "LGPL-3.0-or-later",
+	"agpl":                                 "LicenseRef-AGPL",
+	"agplv3":                               "AGPL-3.0-only",
+	"agplv3+":                              "AGPL-3.0-or-later",
+	"unlicense":                            "Unlicense",
+	"the unlicense":                        "Unlicense",
+	"public domain":                        "LicenseRef-Public-Domain",
+	"zlib/libpng":                          "Zlib",
+	"boost software license":               "BSL-1.0",
+	"hpnd":                                 "HPND",
+	"proprietary":                          "LicenseRef-Proprietary",
+}
+
+// trove classifiers, each a license of its own, so several of them
+// mean the package can be taken under any one
+var classifiers = map[string]string{
+	"License :: OSI Approved :: MIT License":                                             "MIT",
+	"License :: OSI Approved :: MIT No Attribution License (MIT-0)":                      "MIT-0",
+	"License :: OSI Approved :: Apache Software License":                                 "Apache-2.0",
+	"License :: OSI Approved :: BSD License":                                             "LicenseRef-BSD",
+	"License :: OSI Approved :: ISC License (ISCL)":                                      "ISC",
+	"License :: OSI Approved :: Python Software Foundation License":                      "PSF-2.0",
+	"License :: OSI Approved :: Mozilla Public License 1.1 (MPL 1.1)":                    "MPL-1.1",
+	"License :: OSI Approved :: Mozilla Public License 2.0 (MPL 2.0)":                    "MPL-2.0",
+	"License :: OSI Approved :: GNU General Public License (GPL)":                        "LicenseRef-GPL",
+	"License :: OSI Approved :: GNU General Public License v2 (GPLv2)":                   "GPL-2.0-only",
+	"License :: OSI Approved :: GNU General Public License v2 or later (GPLv2+)":         "GPL-2.0-or-later",
+	"License :: OSI Approved :: GNU General Public License v3 (GPLv3)":                   "GPL-3.0-only",
+	"License :: OSI Approved :: GNU General Public License v3 or later (GPLv3+)":         "GPL-3.0-or-later",
+	"License :: OSI Approved :: GNU Library or Lesser General Public License (LGPL)":     "LicenseRef-LGPL",
+	"License :: OSI Approved :: GNU Lesser General Public License v2 (LGPLv2)":           "LGPL-2.0-only",
+	"License :: OSI Approved :: GNU Lesser General Public License v2 or later (LGPLv2+)": "LGPL-2.0-or-later",
+	"License :: OSI Approved :: GNU Lesser General Public License v3 (LGPLv3)":           "LGPL-3.0-only",
+	"License :: OSI Approved :: GNU Lesser General Public License v3 or later (LGPLv3+)": "LGPL-3.0-or-later",
+	"License :: OSI Approved :: GNU Affero General Public License v3":                    "AGPL-3.0-only",
+	"License :: OSI Approved :: GNU Affero General Public License v3 or later (AGPLv3+)": "AGPL-3.0-or-later",
+	"License :: OSI Approved :: Eclipse Public License 1.0 (EPL-1.0)":                    "EPL-1.0",
+	"License :: OSI Approved :: Eclipse Public License 2.0 (EPL-2.0)":                    "EPL-2.0",
+	"License :: OSI Approved :: European Union Public Licence 1.2 (EUPL 1.2)":            "EUPL-1.2",
+	"License :: OSI Approved :: Boost Software License 1.0 (BSL-1.0)":                    "BSL-1.0",
+	"License :: OSI Approved :: Historical Permission Notice and Disclaimer (HPND)":      "HPND",
+	"License :: OSI Approved :: The Unlicense (Unlicense)":                               "Unlicense",
+	"License :: OSI Approved :: Universal Permissive License (UPL)":                      "UPL-1.0",
+	"License :: OSI Approved :: zlib/libpng License":                                     "Zlib",
+	"License :: OSI Approved :: Zope Public License":                                     "ZPL-2.1",
+	"License :: CC0 1.0 Universal (CC0 1.0) Public Domain Dedication":                    "CC0-1.0",
+	"License :: Public Domain":                                                           "LicenseRef-Public-Domain",
+	"License :: Other/Proprietary License":                                               "LicenseRef-Proprietary",
+}
+
+// phrases from the license texts people paste into the License field,
+// checked in order, the GNU ones from most to least specific
+var texts = []struct {
+	phrases []string
+	id      string
+}{
+	{[]string{"gnu affero general public license", "version 3"}, "AGPL-3.0"},
+	{[]string{"gnu lesser general public license", "version 3"}, "LGPL-3.0"},
+	{[]string{"gnu lesser general public license", "version 2.1"}, "LGPL-2.1"},
+	{[]string{"gnu library general public license", "version 2"}, "LGPL-2.0"},
+	{[]string{"gnu general public license", "version 3"}, "GPL-3.0"},
+	{[]string{"gnu general public license", "version 2"}, "GPL-2.0"},
+	{[]string{"apache license", "version 2.0"}, "Apache-2.0"},
+	{[]string{"mozilla public license", "2.0"}, "MPL-2.0"},
+	{[]string{"permission is hereby granted, free of charge"}, "MIT"},
+	{[]string{"permission to use, copy, modify, and/or distribute this software for any purpose"}, "ISC"},
+	{[]string{"redistribution and use in source and binary forms", "endorse or promote"}, "BSD-3-Clause"},
+	{[]string{"redistribution and use in source and binary forms"}, "BSD-2-Clause"},
+}
+
+var spaces = regexp.MustCompile(`\s+`)
+
+// Normalize works out the SPDX expression of a release from its
+// metadata: a PEP 639 License-Expression first, then the License
+// field, then the license classifiers. Source says which one it came
+// from; both are empty when none of them say anything usable.
+func Normalize(expression, text string, troves []string) (spdx string, source string) {
+	if expression = strings.TrimSpace(expression); expression != "" {
+		// PyPI validates these, so ids we don't know are kept as is
+		if canonical, ok := canonicalExpression(expression, false); ok {
+			return canonical, "license_expression"
+		}
+	}
+	if id, ok := fromText(text); ok {
+		return id, "license"
+	}
+	ids := []string{}
+	for _, trove := range troves {
+		if id, ok := classifiers[strings.TrimSpace(trove)]; ok && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > 0 {
+		return strings.Join(ids, " OR "), "classifiers"
+	}
+	return "", ""
+}
+
+func fromText(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	normalized := strings.TrimSuffix(strings.ToLower(spaces.ReplaceAllString(text, " ")), ".")
+	switch normalized {
+	case "", "unknown", "none", "n/a", "other", "see license", "see license file", "license":
+		return "", false
+	}
+
+	// a whole license text rather than a name
+	if strings.Contains(text, "\n") || len(text) > 100 {
+		for _, t := range texts {
+			if containsAll(normalized, t.phrases) {
+				if strings.Contains(t.id, "GPL") {
+					if strings.Contains(normalized, "any later version") {
+						return t.id + "-or-later", true
+					}
+					return t.id + "-only", true
+				}
+				return t.id, true
+			}
+		}
+		return "", false
+	}
+
+	if canonical, ok := canonicalExpression(text, true); ok {
+		return canonical, true
+	}
+	if id, ok := aliases[normalized]; ok {
+		return id, true
+	}
+	return "", false
+}
+
+func containsAll(s string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if !strings.Contains(s, phrase) {
+			return false
+		}
+	}
+	return true
+}
+
+// canonicalExpression fixes up the case of the ids and operators in an
+// SPDX expression. With strict, ids we don't know make it fail, since
+// the License field is free text and `MIT or whatever` isn't SPDX.
+func canonicalExpression(expression string, strict bool) (string, bool) {
+	tokens := tokenize(expression)
+	if len(tokens) == 0 {
+		return "", false
+	}
+	out := []string{}
+	afterWith := false
+	for _, token := range tokens {
+		switch upper := strings.ToUpper(token); {
+		case token == "(" || token == ")":
+			out = append(out, token)
+		case upper == "AND" || upper == "OR" || upper == "WITH":
+			out = append(out, upper)
+			afterWith = upper == "WITH"
+			continue
+		case afterWith:
+			// exceptions like Classpath-exception-2.0, kept as they are
+			out = append(out, token)
+		case strings.HasPrefix(strings.ToLower(token), "licenseref-"):
+			out = append(out, token)
+		default:
+			id, ok := spdxIDs[strings.ToLower(token)]
+			if !ok {
+				if strict {
+					return "", false
+				}
+				id = token
+			}
+			out = append(out, id)
+		}
+		afterWith = false
+	}
+	if _, err := parse(out); err != nil {
+		return "", false
+	}
+	joined := strings.Join(out, " ")
+	joined = strings.ReplaceAll(strings.ReplaceAll(joined, "( ", "("), " )", ")")
+	return joined, true
+}
+
+func tokenize(expression string) []string {
+	expression = strings.ReplaceAll(strings.ReplaceAll(expression, "(", " ( "), ")", " ) ")
+	return strings.Fields(expression)
+}
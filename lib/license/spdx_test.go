@@ -0,0 +1,86 @@
+package license
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		text       string
+		troves     []string
+		spdx       string
+		source     string
+	}{
+		{name: "expression", expression: "mit or apache-2.0", spdx: "MIT OR Apache-2.0", source: "license_expression"},
+		{name: "expression with exception", expression: "GPL-2.0-or-later WITH Classpath-exception-2.0", spdx: "GPL-2.0-or-later WITH Classpath-exception-2.0", source: "license_expression"},
+		{name: "unknown ids are kept in an expression", expression: "(Foo-1.0 AND mit)", spdx: "(Foo-1.0 AND MIT)", source: "license_expression"},
+		{name: "id in the license field", text: "bsd-3-clause", spdx: "BSD-3-Clause", source: "license"},
+		{name: "alias", text: "Apache License, Version 2.0", spdx: "Apache-2.0", source: "license"},
+		{name: "license text", text: "Permission is hereby granted, free of charge, to any person\nobtaining a copy", spdx: "MIT", source: "license"},
+		{name: "GPL text or later", text: "GNU General Public License\nversion 3, or (at your option) any later version.", spdx: "GPL-3.0-or-later", source: "license"},
+		{name: "GPL text only", text: "GNU General Public License\nversion 2 of the License.", spdx: "GPL-2.0-only", source: "license"},
+		{name: "free text isn't SPDX", text: "MIT or whatever you like", troves: []string{"License :: OSI Approved :: MIT License"}, spdx: "MIT", source: "classifiers"},
+		{
+			name: "several classifiers", text: "UNKNOWN",
+			troves: []string{"License :: OSI Approved :: MIT License", "License :: OSI Approved :: Apache Software License", "License :: OSI Approved :: MIT License"},
+			spdx:   "MIT OR Apache-2.0", source: "classifiers",
+		},
+		{name: "nothing", text: "see LICENSE file", spdx: "", source: ""},
+		{name: "broken expression", expression: "MIT AND", spdx: "", source: ""},
+	}
+	for _, test := range tests {
+		spdx, source := Normalize(test.expression, test.text, test.troves)
+		if spdx != test.spdx || source != test.source {
+			t.Errorf("%s: Normalize() = %q, %q, want %q, %q", test.name, spdx, source, test.spdx, test.source)
+		}
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy := &Policy{Deny: []string{"GPL-*", "AGPL-*"}}
+	tests := []struct {
+		expression string
+		blocked    []string
+	}{
+		{"MIT", nil},
+		{"GPL-3.0-only", []string{"GPL-3.0-only"}},
+		{"MIT OR GPL-3.0-only", nil},
+		{"MIT AND GPL-3.0-only", []string{"GPL-3.0-only"}},
+		{"(MIT OR GPL-2.0-only) AND AGPL-3.0-only", []string{"GPL-2.0-only", "AGPL-3.0-only"}},
+		// AND binds tighter than OR
+		{"MIT OR GPL-2.0-only AND AGPL-3.0-only", nil},
+		{"gpl-2.0-or-later WITH Classpath-exception-2.0", []string{"gpl-2.0-or-later"}},
+	}
+	for _, test := range tests {
+		blocked, err := policy.Check(test.expression)
+		if err != nil {
+			t.Errorf("Check(%q) error: %v", test.expression, err)
+			continue
+		}
+		if len(blocked) != len(test.blocked) {
+			t.Errorf("Check(%q) = %v, want %v", test.expression, blocked, test.blocked)
+			continue
+		}
+		for i := range blocked {
+			if blocked[i] != test.blocked[i] {
+				t.Errorf("Check(%q) = %v, want %v", test.expression, blocked, test.blocked)
+				break
+			}
+		}
+	}
+
+	for _, bad := range []string{"", "MIT AND", "(MIT", "MIT)", "OR MIT", "MIT WITH"} {
+		if _, err := policy.Check(bad); err == nil {
+			t.Errorf("Check(%q) should fail", bad)
+		}
+	}
+
+	allowList := &Policy{Allow: []string{"MIT", "BSD-*"}}
+	if !allowList.Allowed("bsd-3-clause") || allowList.Allowed("Apache-2.0") {
+		t.Errorf("Allowed() doesn't follow the allow list")
+	}
+	var none *Policy
+	if !none.Allowed("GPL-3.0-only") {
+		t.Errorf("a nil policy should allow everything")
+	}
+}
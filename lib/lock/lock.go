@@ -0,0 +1,161 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	"github.com/DerekCorniello/pip-req-valid/resolve"
+)
+
+// Entry is one pinned package of a lockfile.
+type Entry struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	URL     string `json:"url,omitempty"`
+	// `sha256:<hex>` for every file the index has for the version, so
+	// pip accepts whichever one it picks
+	Hashes []string `json:"hashes"`
+	// the requirements files that ask for the package directly
+	Sources []string `json:"sources,omitempty"`
+	// the locked packages that depend on it
+	RequiredBy []string `json:"required_by,omitempty"`
+}
+
+// Lockfile is every package a requirements file ends up installing on
+// one target, each at an exact version.
+type Lockfile struct {
+	Target string `json:"target"`
+	// the indexes the requirements file named, so the lockfile installs
+	// from the same place it was built against
+	IndexURL       string   `json:"index_url,omitempty"`
+	ExtraIndexURLs []string `json:"extra_index_urls,omitempty"`
+	Packages       []Entry  `json:"packages"`
+}
+
+// ErrMissingHash is why Build fails when a pin has nothing to hash. pip's
+// hash-checking mode rejects the whole file if a single line goes
+// without one, so a partly hashed lockfile wouldn't install at all.
+var ErrMissingHash = errors.New("pip's hash-checking mode needs a sha256 hash for every package")
+
+// Build turns a resolution into a lockfile, looking up the hashes of
+// every distribution file of each pinned version on idx. Direct urls
+// and versions without a sha256 digest can't be hashed, those fail with
+// ErrMissingHash. A pin whose metadata wasn't read fails with
+// index.ErrNoMetadata, its dependencies would be missing from the
+// lockfile.
+func Build(ctx context.Context, idx index.PackageIndex, target string, resolution *resolve.Resolution) (*Lockfile, error) {
+	lockfile := &Lockfile{Target: target, Packages: []Entry{}}
+	unhashed := []string{}
+	unknown := []string{}
+	for _, pin := range resolution.Pins {
+		if pin.URL == "" && pin.Release == nil {
+			unknown = append(unknown, pin.String())
+		}
+		entry := Entry{
+			Name:       pin.Name,
+			Version:    pin.Version,
+			URL:        pin.URL,
+			Hashes:     []string{},
+			Sources:    pin.Sources,
+			RequiredBy: pin.RequiredBy,
+		}
+		// there's nothing to hash for a url without downloading it
+		if pin.URL == "" {
+			files, err := idx.Files(ctx, pin.Name, pin.Version)
+			if err != nil {
+				return nil, fmt.Errorf("error listing files of %s: %w", pin, err)
+			}
+			for _, file := range files {
+				if digest, ok := file.Digests["sha256"]; ok {
+					entry.Hashes = append(entry.Hashes, "sha256:"+digest)
+				}
+			}
+			sort.Strings(entry.Hashes)
+		}
+		if len(entry.Hashes) == 0 {
+			unhashed = append(unhashed, pin.String())
+		}
+		lockfile.Packages = append(lockfile.Packages, entry)
+	}
+	if len(unhashed) > 0 {
+		return nil, fmt.Errorf("no hash for %s: %w", strings.Join(unhashed, ", "), ErrMissingHash)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("the dependencies of %s aren't known: %w", strings.Join(unknown, ", "), index.ErrNoMetadata)
+	}
+	return lockfile, nil
+}
+
+// SetIndexes records the index options of the requirements file. Any
+// credentials in the urls are left out, they'd end up in a file that
+// gets shared, pip can get them from its keyring or netrc instead.
+func (l *Lockfile) SetIndexes(indexURL string, extraIndexURLs []string) {
+	l.IndexURL = ""
+	if indexURL != "" {
+		l.IndexURL = withoutCredentials(indexURL)
+	}
+	l.ExtraIndexURLs = nil
+	for _, extra := range extraIndexURLs {
+		l.ExtraIndexURLs = append(l.ExtraIndexURLs, withoutCredentials(extra))
+	}
+}
+
+func withoutCredentials(indexURL string) string {
+	parsed, err := url.Parse(indexURL)
+	if err != nil || parsed.User == nil {
+		return indexURL
+	}
+	parsed.User = nil
+	return parsed.String()
+}
+
+// Requirements writes the lockfile as a requirements file, laid out
+// like `pip-compile --generate-hashes` does it.
+func (l *Lockfile) Requirements() string {
+	var sb strings.Builder
+	sb.WriteString("#\n")
+	fmt.Fprintf(&sb, "# This file is autogenerated by pip-req-valid for %s.\n", l.Target)
+	sb.WriteString("#\n")
+	if l.IndexURL != "" {
+		sb.WriteString("--index-url " + l.IndexURL + "\n")
+	}
+	for _, extra := range l.ExtraIndexURLs {
+		sb.WriteString("--extra-index-url " + extra + "\n")
+	}
+	if l.IndexURL != "" || len(l.ExtraIndexURLs) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, entry := range l.Packages {
+		if entry.URL != "" {
+			sb.WriteString(entry.Name + " @ " + entry.URL)
+		} else {
+			sb.WriteString(entry.Name + "==" + entry.Version)
+		}
+		for _, hash := range entry.Hashes {
+			sb.WriteString(" \\\n    --hash=" + hash)
+		}
+		sb.WriteString("\n")
+
+		via := []string{}
+		for _, source := range entry.Sources {
+			via = append(via, "-r "+source)
+		}
+		via = append(via, entry.RequiredBy...)
+		switch len(via) {
+		case 0:
+		case 1:
+			sb.WriteString("    # via " + via[0] + "\n")
+		default:
+			sb.WriteString("    # via\n")
+			for _, v := range via {
+				sb.WriteString("    #   " + v + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
@@ -10,11 +10,24 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/DerekCorniello/pip-req-valid/index"
 	"github.com/DerekCorniello/pip-req-valid/input"
+	"github.com/DerekCorniello/pip-req-valid/license"
+	"github.com/DerekCorniello/pip-req-valid/lock"
 	"github.com/DerekCorniello/pip-req-valid/output"
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+	"github.com/DerekCorniello/pip-req-valid/resolve"
+	"github.com/DerekCorniello/pip-req-valid/typosquat"
+	"github.com/DerekCorniello/pip-req-valid/utils"
+	"github.com/DerekCorniello/pip-req-valid/vuln"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/joho/godotenv"
@@ -23,6 +36,24 @@ import (
 
 var jwtKey []byte
 
+// credentials for private indexes, read from the file INDEX_AUTH_FILE
+// points at, and the on-disk cache set up from the CACHE_* variables
+var indexConfig index.Config
+
+// OSV advisories to check packages against, loaded from OSV_DB
+var vulnDB *vuln.DB
+
+// which licenses may be shipped, read from the file LICENSE_POLICY
+var licensePolicy *license.Policy
+
+// internal package names to guard against dependency confusion, from
+// INTERNAL_PACKAGES and PUBLIC_INDEX_URL
+var confusionGuard *input.ConfusionGuard
+
+// the names typos are checked against, POPULAR_PACKAGES replaces the
+// bundled list
+var popularNames *typosquat.List
+
 func generateRandomKey() []byte {
 	key := make([]byte, 32)
 	_, err := rand.Read(key)
@@ -69,35 +100,113 @@ func RateLimitMiddleware(limiter *rate.Limiter, next http.Handler) http.Handler
 	})
 }
 
-func RunDockerInstall(requirements []byte) (string, error) {
+// RunDockerInstall does a real pip install of the file in a throwaway
+// container, with the files it includes next to it. Failures pip
+// reports for a specific requirement are pointed back at that
+// requirement in pkgs.
+func RunDockerInstall(name string, requirements []byte, includes input.MapResolver, pkgs []utils.Package) (string, []utils.Diagnostic, error) {
 	log.Printf("Starting RunDockerInstall")
-	// save the file temporarily
-	tmpFile, err := os.CreateTemp("/host_tmp", "requirements-*.txt")
+	// save the files temporarily
+	tmpDir, err := os.MkdirTemp("/host_tmp", "requirements-*")
 	if err != nil {
-		return "", fmt.Errorf("could not create temp file: %v", err)
+		return "", nil, fmt.Errorf("could not create temp dir: %v", err)
 	}
-	defer os.Remove(tmpFile.Name())
+	defer os.RemoveAll(tmpDir)
 
-	// write the requirements.txt content to the temporary file
-	_, err = tmpFile.Write(requirements)
-	if err != nil {
-		return "", fmt.Errorf("could not write to temp file: %v", err)
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		name = "requirements.txt"
+	}
+	if err := writeInstallFiles(tmpDir, name, requirements, includes); err != nil {
+		return "", nil, fmt.Errorf("could not write to temp dir: %v", err)
 	}
-	tmpFile.Close()
 
-	hostPath := strings.Replace(tmpFile.Name(), "/host_tmp", "/tmp", 1)
+	hostPath := strings.Replace(tmpDir, "/host_tmp", "/tmp", 1)
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
-	cmd := exec.CommandContext(ctx, "docker", "run", "--rm", "-v", fmt.Sprintf("%s:/app/requirements.txt", hostPath), "my-python-git", "sh", "-c", "mkdir -p /app && pip install --progress-bar off --disable-pip-version-check --no-cache-dir --root-user-action ignore -r /app/requirements.txt")
+	cmd := exec.CommandContext(ctx, "docker", "run", "--rm", "-v", fmt.Sprintf("%s:/app", hostPath), "my-python-git",
+		"pip", "install", "--progress-bar", "off", "--disable-pip-version-check", "--no-cache-dir", "--root-user-action", "ignore", "-r", path.Join("/app", name))
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		if err == context.DeadlineExceeded {
-			return "Pip install timed out after 30 seconds.", nil
+		if ctx.Err() == context.DeadlineExceeded {
+			diag := utils.NewDiagnostic(utils.Position{}, utils.SeverityWarning, utils.CodeInstallFailed,
+				"Pip install timed out after 30 seconds.")
+			return "Pip install timed out after 30 seconds.", []utils.Diagnostic{diag}, nil
+		}
+		return fmt.Sprintf("Pip install failed: %s", string(output)), installDiagnostics(string(output), pkgs), nil
+	}
+
+	return string(output), nil, nil
+}
+
+// writeInstallFiles lays an upload out in dir the way its `-r` and `-c`
+// lines expect, the main file under its name and every include at its
+// path relative to that. Names that would land outside dir are left
+// out, pip can't open those the same way the static check couldn't.
+func writeInstallFiles(dir string, name string, requirements []byte, includes input.MapResolver) error {
+	files := map[string][]byte{}
+	for include, content := range includes {
+		files[include] = content
+	}
+	files[name] = requirements
+	for file, content := range files {
+		local := filepath.FromSlash(file)
+		if !filepath.IsLocal(local) {
+			continue
+		}
+		target := filepath.Join(dir, local)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, content, 0o644); err != nil {
+			return err
 		}
-		return fmt.Sprintf("Pip install failed: %s", string(output)), nil
 	}
+	return nil
+}
+
+var (
+	// the two ways pip says a requirement couldn't be found
+	pipNoVersionRe      = regexp.MustCompile(`satisfies the requirement (\S+)`)
+	pipNoDistributionRe = regexp.MustCompile(`No matching distribution found for (\S+)`)
+	requirementNameRe   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*`)
+)
 
-	return string(output), nil
+// installDiagnostics turns the ERROR lines of pip's output into
+// diagnostics, matching them to a package when pip names one.
+func installDiagnostics(output string, pkgs []utils.Package) []utils.Diagnostic {
+	diags := []utils.Diagnostic{}
+	reported := map[string]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "ERROR:") {
+			continue
+		}
+		msg := strings.TrimSpace(strings.TrimPrefix(line, "ERROR:"))
+
+		var pos utils.Position
+		var requirement string
+		if m := pipNoVersionRe.FindStringSubmatch(msg); m != nil {
+			requirement = m[1]
+		} else if m := pipNoDistributionRe.FindStringSubmatch(msg); m != nil {
+			requirement = m[1]
+		}
+		if name := requirementNameRe.FindString(requirement); name != "" {
+			// pip prints both lines for the same failure, one is enough
+			name = pep508.NormalizeName(name)
+			if reported[name] {
+				continue
+			}
+			reported[name] = true
+			for _, pkg := range pkgs {
+				if pkg.CanonicalName() == name {
+					pos = pkg.Position
+					break
+				}
+			}
+		}
+		diags = append(diags, utils.NewDiagnostic(pos, utils.SeverityError, utils.CodeInstallFailed, "%s", msg))
+	}
+	return diags
 }
 
 func validateToken(tokenString string) (*jwt.Token, error) {
@@ -120,8 +229,22 @@ func validateToken(tokenString string) (*jwt.Token, error) {
 	return nil, fmt.Errorf("invalid token")
 }
 
-func handleRequest(writer http.ResponseWriter, reader *http.Request) {
-	log.Printf("Main request received, method: %s", reader.Method)
+// upload is a requirements file posted to one of the endpoints, parsed
+// along with the targets it's checked for.
+type upload struct {
+	content    []byte
+	reqFile    *input.RequirementsFile
+	parseDiags []utils.Diagnostic
+	targets    []input.Target
+	// the files it pulls in with `-r` and `-c`, by name
+	includes input.MapResolver
+}
+
+// readUpload does what every endpoint starts with: CORS, the method and
+// token checks, and parsing the uploaded file. When it returns false it
+// has already answered the request.
+func readUpload(writer http.ResponseWriter, reader *http.Request) (*upload, bool) {
+	log.Printf("Request to %s received, method: %s", reader.URL.Path, reader.Method)
 	// Set CORS headers
 	origin := reader.Header.Get("Origin")
 	if origin == "" {
@@ -133,66 +256,216 @@ func handleRequest(writer http.ResponseWriter, reader *http.Request) {
 
 	// Handle preflight OPTIONS request
 	if reader.Method == http.MethodOptions {
-		log.Printf("Handling OPTIONS for main request")
+		log.Printf("Handling OPTIONS for %s", reader.URL.Path)
 		writer.WriteHeader(http.StatusOK)
-		return
+		return nil, false
 	}
 
 	if reader.Method != http.MethodPost && reader.Method != http.MethodOptions {
-		log.Printf("Invalid method for main request: %s", reader.Method)
+		log.Printf("Invalid method for %s: %s", reader.URL.Path, reader.Method)
 		http.Error(writer, "Only POST method is allowed", http.StatusMethodNotAllowed)
-		return
+		return nil, false
 	}
 
 	auth := reader.Header.Get("Authorization")
 
 	if !strings.HasPrefix(auth, "Bearer ") {
 		http.Error(writer, "Unauthenticated Request: Missing Bearer Token", http.StatusUnauthorized)
-		return
+		return nil, false
 	}
 	tokenString := auth[len("Bearer "):]
 	_, err := validateToken(tokenString)
 	if err != nil {
 		http.Error(writer, fmt.Sprintf("Unauthenticated Request: %s", err.Error()), http.StatusUnauthorized)
-		return
+		return nil, false
 	}
 
 	contentType := reader.Header.Get("Content-Type")
 	if !strings.HasPrefix(contentType, "multipart/form-data") {
 		http.Error(writer, "Expected multipart/form-data", http.StatusBadRequest)
-		return
+		return nil, false
 	}
 
 	fileContent, err := parseMultipartForm(reader)
 	if err != nil {
 		log.Println("Error parsing form data:", err)
 		http.Error(writer, "Error parsing file content", http.StatusBadRequest)
-		return
+		return nil, false
 	}
 	log.Printf("Parsed multipart form, file size: %d", len(fileContent))
 
-	pkgs, errs := input.ParseFile(fileContent)
-	log.Printf("Parsed file, packages: %d, errors: %d", len(pkgs), len(errs))
+	includes, err := parseIncludeFiles(reader)
+	if err != nil {
+		log.Println("Error reading included files:", err)
+		http.Error(writer, "Error parsing included files", http.StatusBadRequest)
+		return nil, false
+	}
 
-	errList := []string{}
-	for _, err := range errs {
-		errList = append(errList, err.Error())
+	// no env here, uploaded files shouldn't be able to read the server's
+	// environment variables through ${VAR}
+	reqFile, parseDiags := input.ParseFile(uploadName(reader), fileContent, includes, nil)
+	log.Printf("Parsed file, packages: %d, constraints: %d, diagnostics: %d", len(reqFile.Requirements), len(reqFile.Constraints), len(parseDiags))
+
+	// which environments to evaluate markers for, like
+	// `py3.12-linux-x86_64,py3.12-windows-amd64`
+	targets, err := input.ParseTargets(reader.FormValue("targets"))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	return &upload{content: fileContent, reqFile: reqFile, parseDiags: parseDiags, targets: targets, includes: includes}, true
+}
+
+func handleRequest(writer http.ResponseWriter, reader *http.Request) {
+	upload, ok := readUpload(writer, reader)
+	if !ok {
+		return
+	}
+	fileContent, reqFile, parseDiags, targets := upload.content, upload.reqFile, upload.parseDiags, upload.targets
+
+	// interpreters to check Requires-Python against, like `3.8,3.12`
+	pythons, err := input.ParsePythons(reader.FormValue("pythons"))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// a configured policy always applies, otherwise licenses are only
+	// looked up when asked for
+	licenses := licensePolicy
+	if showLicenses, _ := strconv.ParseBool(reader.FormValue("licenses")); showLicenses && licenses == nil {
+		licenses = &license.Policy{}
+	}
+
+	// health needs the metadata of every latest release, so it's only
+	// looked at when asked for
+	var health *input.HealthConfig
+	if checkHealth, _ := strconv.ParseBool(reader.FormValue("health")); checkHealth {
+		health = &input.HealthConfig{}
+	}
+
+	verPkgs, invPkgs, verifyDiags := input.VerifyPackages(reader.Context(), reqFile.Requirements, reqFile.Options, input.VerifyConfig{IndexConfig: indexConfig, Targets: targets, Pythons: pythons, Vulnerabilities: vulnDB, Licenses: licenses, PopularNames: popularNames, Confusion: confusionGuard, Health: health})
+
+	// the targets each checked requirement gets installed on
+	checked := slices.Concat(verPkgs, invPkgs)
+	packageTargets := map[string][]string{}
+	sourceBuilds := map[string][]string{}
+	for _, pkg := range checked {
+		packageTargets[pkg.Name] = pkg.Targets
+		if len(pkg.SourceBuilds) > 0 {
+			sourceBuilds[pkg.Name] = pkg.SourceBuilds
+		}
+	}
+
+	// resolving reads the metadata of every candidate release, so it
+	// only runs when asked for
+	var resolutions map[string]*resolve.Resolution
+	if resolveDeps, _ := strconv.ParseBool(reader.FormValue("resolve")); resolveDeps {
+		var resolveDiags []utils.Diagnostic
+		resolutions, resolveDiags = input.ResolveRequirements(reader.Context(), reqFile, input.VerifyConfig{IndexConfig: indexConfig, Targets: targets})
+		verifyDiags = append(verifyDiags, resolveDiags...)
 	}
 
-	verPkgs, invPkgs, details := input.VerifyPackages(pkgs)
+	// a lockfile only makes sense once the file checks out
+	var lockfiles map[string]*lock.Lockfile
+	lockfileText := map[string]string{}
+	if lockDeps, _ := strconv.ParseBool(reader.FormValue("lock")); !lockDeps {
+		// not asked for
+	} else if errs := utils.Errors(slices.Concat(parseDiags, verifyDiags)); len(errs) > 0 {
+		verifyDiags = append(verifyDiags, utils.NewDiagnostic(utils.Position{File: uploadName(reader)}, utils.SeverityError, utils.CodeLockSkipped,
+			"No lockfile was produced, the requirements have %d errors to fix first.", len(errs)))
+	} else {
+		var lockDiags []utils.Diagnostic
+		lockfiles, lockDiags = input.LockRequirements(reader.Context(), reqFile, input.VerifyConfig{IndexConfig: indexConfig, Targets: targets})
+		verifyDiags = append(verifyDiags, lockDiags...)
+		for target, lockfile := range lockfiles {
+			lockfileText[target] = lockfile.Requirements()
+		}
+	}
+
+	installOutput, installDiags, installErr := RunDockerInstall(uploadName(reader), fileContent, upload.includes, reqFile.Requirements)
 
-	installOutput, installErr := RunDockerInstall(fileContent)
+	errList := []string{}
+	for _, diag := range utils.Errors(parseDiags) {
+		errList = append(errList, diag.String())
+	}
 	if installErr != nil {
 		errList = append(errList, installErr.Error())
 	}
 
+	details := []string{}
+	for _, diag := range verifyDiags {
+		details = append(details, diag.String())
+	}
+
+	diagnostics := slices.Concat(parseDiags, verifyDiags, installDiags)
+
+	// corrected copies of the files that had something to fix, only
+	// built when asked for
+	fixes := []input.Fix{}
+	fixedFiles := map[string]string{}
+	if fix, _ := strconv.ParseBool(reader.FormValue("fix")); fix {
+		docs := []*input.Document{}
+		for _, name := range reqFile.Files {
+			content, ok := upload.includes[name]
+			if name == uploadName(reader) {
+				content, ok = fileContent, true
+			}
+			if ok {
+				docs = append(docs, input.NewDocument(name, content))
+			}
+		}
+		fixes = input.FixFiles(docs, diagnostics)
+		for _, doc := range docs {
+			if slices.ContainsFunc(fixes, func(fix input.Fix) bool { return fix.File == doc.Name }) {
+				fixedFiles[doc.Name] = string(doc.Bytes())
+			}
+		}
+	}
+
 	response := map[string]interface{}{
-		"prettyOutput":  output.GetPrettyOutput(verPkgs, invPkgs, errs), // formatted output
-		"details":       strings.Join(details, "\n"),                    // details of the process
-		"errors":        strings.Join(errList, "\n"),                    // errors occurred during processing
-		"installOutput": installOutput,                                  // test install output
+		"prettyOutput":    output.GetPrettyOutput(verPkgs, invPkgs, parseDiags),     // formatted output
+		"details":         strings.Join(details, "\n"),                              // details of the process
+		"errors":          strings.Join(errList, "\n"),                              // errors occurred during processing
+		"installOutput":   installOutput,                                            // test install output
+		"diagnostics":     diagnostics,                                              // every finding, with its position
+		"targets":         packageTargets,                                           // which targets each package applies to
+		"sourceBuilds":    sourceBuilds,                                             // targets without a wheel for a package
+		"vulnerabilities": input.BuildVulnerabilityReport(checked, reqFile.Files),   // known advisories, if OSV_DB is set
+		"licenses":        input.BuildLicenseReport(checked),                        // package names by SPDX license, if licenses were checked
+		"health":          input.BuildHealthReport(checked, reqFile.Files),          // risk signals per package, if health was set
+		"pythonMatrix":    input.BuildPythonMatrix(pythons, checked, reqFile.Files), // what installs on each interpreter, if pythons was set
+		"resolution":      resolutions,                                              // pinned versions by target, if resolve was set
+		"lockfiles":       lockfiles,                                                // lockfiles by target, if lock was set
+		"lockfileText":    lockfileText,                                             // the same as requirements files
+		"fixes":           fixes,                                                    // edits made to correct the files, if fix was set
+		"fixedFiles":      fixedFiles,                                               // the corrected files by name
+	}
+
+	log.Printf("Sending response for main request")
+	writeJSON(writer, response)
+}
+
+// handleOutdated is the outdated report mode: for every package that
+// verifies, the latest release, the latest the specifiers allow and the
+// latest that still resolves with the rest of the file.
+func handleOutdated(writer http.ResponseWriter, reader *http.Request) {
+	upload, ok := readUpload(writer, reader)
+	if !ok {
+		return
 	}
 
+	outdated, verifyDiags := input.CheckOutdated(reader.Context(), upload.reqFile, input.VerifyConfig{IndexConfig: indexConfig, Targets: upload.targets, PopularNames: popularNames})
+
+	log.Printf("Sending response for outdated request, packages: %d", len(outdated))
+	writeJSON(writer, map[string]interface{}{
+		"outdated":    outdated,                                      // how far behind each verified package is
+		"diagnostics": slices.Concat(upload.parseDiags, verifyDiags), // every finding, with its position
+	})
+}
+
+func writeJSON(writer http.ResponseWriter, response map[string]interface{}) {
 	jsonResponse, err := json.Marshal(response)
 	if err != nil {
 		log.Printf("Failed to marshal JSON: %v", err)
@@ -200,7 +473,6 @@ func handleRequest(writer http.ResponseWriter, reader *http.Request) {
 		return
 	}
 
-	log.Printf("Sending response for main request")
 	writer.Header().Set("Content-Type", "application/json")
 	writer.WriteHeader(http.StatusOK)
 	if _, err := writer.Write(jsonResponse); err != nil {
@@ -229,6 +501,40 @@ func parseMultipartForm(reader *http.Request) ([]byte, error) {
 	return fileContent, nil
 }
 
+// parseIncludeFiles collects any extra files uploaded under "includes",
+// these are what `-r` and `-c` lines get resolved against. It has to run
+// after parseMultipartForm.
+func parseIncludeFiles(reader *http.Request) (input.MapResolver, error) {
+	includes := input.MapResolver{}
+	if reader.MultipartForm == nil {
+		return includes, nil
+	}
+	for _, header := range reader.MultipartForm.File["includes"] {
+		file, err := header.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open included file %s: %v", header.Filename, err)
+		}
+		content, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read included file %s: %v", header.Filename, err)
+		}
+		includes[path.Clean(header.Filename)] = content
+	}
+	return includes, nil
+}
+
+// uploadName is the name of the main uploaded file, includes are
+// resolved relative to it.
+func uploadName(reader *http.Request) string {
+	if reader.MultipartForm != nil {
+		if headers := reader.MultipartForm.File["file"]; len(headers) > 0 {
+			return path.Clean(headers[0].Filename)
+		}
+	}
+	return "requirements.txt"
+}
+
 func handleAuth(writer http.ResponseWriter, reader *http.Request) {
 	log.Printf("Auth request received, method: %s", reader.Method)
 	// Set CORS headers
@@ -278,11 +584,86 @@ func handleAuth(writer http.ResponseWriter, reader *http.Request) {
 	writer.Write(responseData)
 }
 
+// loadCacheConfig builds the index cache from CACHE_DIR, CACHE_TTL,
+// CACHE_MAX_AGE, CACHE_MAX_BYTES and CACHE_OFFLINE. No CACHE_DIR means
+// no cache.
+func loadCacheConfig() (*index.Cache, error) {
+	dir := os.Getenv("CACHE_DIR")
+	if dir == "" {
+		return nil, nil
+	}
+	cache := &index.Cache{Dir: dir}
+	var err error
+	if ttl := os.Getenv("CACHE_TTL"); ttl != "" {
+		if cache.TTL, err = time.ParseDuration(ttl); err != nil {
+			return nil, fmt.Errorf("CACHE_TTL: %v", err)
+		}
+	}
+	if maxAge := os.Getenv("CACHE_MAX_AGE"); maxAge != "" {
+		if cache.MaxAge, err = time.ParseDuration(maxAge); err != nil {
+			return nil, fmt.Errorf("CACHE_MAX_AGE: %v", err)
+		}
+	}
+	if maxBytes := os.Getenv("CACHE_MAX_BYTES"); maxBytes != "" {
+		if cache.MaxBytes, err = strconv.ParseInt(maxBytes, 10, 64); err != nil {
+			return nil, fmt.Errorf("CACHE_MAX_BYTES: %v", err)
+		}
+	}
+	if offline := os.Getenv("CACHE_OFFLINE"); offline != "" {
+		if cache.Offline, err = strconv.ParseBool(offline); err != nil {
+			return nil, fmt.Errorf("CACHE_OFFLINE: %v", err)
+		}
+	}
+	return cache, nil
+}
+
 func main() {
 	godotenv.Load() // Load .env if exists, otherwise use env vars
+	if authFile := os.Getenv("INDEX_AUTH_FILE"); authFile != "" {
+		auth, err := index.LoadAuthConfig(authFile)
+		if err != nil {
+			log.Fatalf("Failed to load index auth from %s: %v", authFile, err)
+		}
+		indexConfig.Auth = auth
+	}
+	if osvPath := os.Getenv("OSV_DB"); osvPath != "" {
+		db, err := vuln.Load(osvPath)
+		if err != nil {
+			log.Fatalf("Failed to load the vulnerability database from %s: %v", osvPath, err)
+		}
+		log.Printf("Loaded %d advisories from %s", db.Len(), osvPath)
+		vulnDB = db
+	}
+	if policyFile := os.Getenv("LICENSE_POLICY"); policyFile != "" {
+		policy, err := license.LoadPolicy(policyFile)
+		if err != nil {
+			log.Fatalf("Failed to load the license policy from %s: %v", policyFile, err)
+		}
+		licensePolicy = policy
+	}
+	if popularFile := os.Getenv("POPULAR_PACKAGES"); popularFile != "" {
+		list, err := typosquat.Load(popularFile)
+		if err != nil {
+			log.Fatalf("Failed to load popular package names from %s: %v", popularFile, err)
+		}
+		popularNames = list
+	}
+	if internal := os.Getenv("INTERNAL_PACKAGES"); internal != "" {
+		patterns, err := input.ParseInternalPackages(internal)
+		if err != nil {
+			log.Fatalf("Invalid INTERNAL_PACKAGES: %v", err)
+		}
+		confusionGuard = &input.ConfusionGuard{Internal: patterns, PublicURL: os.Getenv("PUBLIC_INDEX_URL")}
+	}
+	cache, err := loadCacheConfig()
+	if err != nil {
+		log.Fatalf("Invalid cache settings: %v", err)
+	}
+	indexConfig.Cache = cache
 	limiter := rate.NewLimiter(rate.Every(time.Minute), 10)
 
 	http.Handle("/", CORSMiddleware(RateLimitMiddleware(limiter, http.HandlerFunc(handleRequest))))
+	http.Handle("/outdated", CORSMiddleware(RateLimitMiddleware(limiter, http.HandlerFunc(handleOutdated))))
 	http.Handle("/auth", CORSMiddleware(RateLimitMiddleware(limiter, http.HandlerFunc(handleAuth))))
 	port := "8080"
 	log.Printf("Server starting on port %s", port)
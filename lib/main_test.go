@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DerekCorniello/pip-req-valid/input"
+)
+
+func TestWriteInstallFiles(t *testing.T) {
+	dir := t.TempDir()
+	includes := input.MapResolver{
+		"base.txt":            []byte("flask\n"),
+		"sub/constraints.txt": []byte("urllib3<2\n"),
+		"../outside.txt":      []byte("evil\n"),
+	}
+	if err := writeInstallFiles(dir, "requirements.txt", []byte("-r base.txt\n-c sub/constraints.txt\n"), includes); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"requirements.txt":    "-r base.txt\n-c sub/constraints.txt\n",
+		"base.txt":            "flask\n",
+		"sub/constraints.txt": "urllib3<2\n",
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil || string(got) != content {
+			t.Errorf("%s = %q, %v, want %q", name, got, err, content)
+		}
+	}
+	// nothing is written outside the directory
+	if _, err := os.Stat(filepath.Join(dir, "..", "outside.txt")); !os.IsNotExist(err) {
+		t.Errorf("../outside.txt was written: %v", err)
+	}
+}
@@ -66,7 +66,7 @@ func createMessage(items []string, msgType MessageType) string {
 }
 
 func GetPrettyOutput(verifiedPackages []utils.Package,
-	errorPackages []utils.Package, errs []error) string {
+	errorPackages []utils.Package, diags []utils.Diagnostic) string {
 
 	// cool, we can use anonymous funcs too! I love Go
 	// create a list of each of the string versions
@@ -75,11 +75,60 @@ func GetPrettyOutput(verifiedPackages []utils.Package,
 		func(pkg utils.Package) string { return pkg.Name })
 	csErrPkgs := extractStrings(errorPackages,
 		func(pkg utils.Package) string { return pkg.Name })
-	csErrs := extractStrings(errs,
-		func(err error) string { return err.Error() })
+	// only the errors are worth calling out here, everything else
+	// is in the full diagnostics list
+	csErrs := extractStrings(utils.Errors(diags),
+		func(d utils.Diagnostic) string { return d.String() })
 
 	s := fmt.Sprintf("%v\n%v\n%v", createMessage(csVerPkgs, MessageType(VerifiedPackages)),
 		createMessage(csErrPkgs, MessageType(ErrorPackages)),
 		createMessage(csErrs, MessageType(ProcessingErrors)))
+	// only there when a vulnerability database was checked and found
+	// something
+	if lines, count := vulnerabilityLines(verifiedPackages); count > 0 {
+		s += fmt.Sprintf("\nFound %d known vulnerabilities:\n        %v", count, strings.Join(lines, "\n        "))
+	}
+	if lines := healthLines(verifiedPackages); len(lines) > 0 {
+		s += fmt.Sprintf("\nHealth report:\n        %v", strings.Join(lines, "\n        "))
+	}
 	return s
 }
+
+// one line per package with a risk signal, like
+// `nose==1.3.7: no release since 2015-06-02, marked inactive`
+func healthLines(packages []utils.Package) []string {
+	lines := []string{}
+	for _, pkg := range packages {
+		if pkg.Health != nil && len(pkg.Health.Signals) > 0 {
+			lines = append(lines, fmt.Sprintf("%s==%s: %s", pkg.Name, pkg.Health.Version, strings.Join(pkg.Health.Signals, ", ")))
+		}
+	}
+	return lines
+}
+
+// one line per advisory, like
+// `requests==2.19.0: GHSA-x84v-xcm2-53pg (high, CVE-2018-18074), fixed in 2.20.0`
+func vulnerabilityLines(packages []utils.Package) ([]string, int) {
+	lines := []string{}
+	count := 0
+	for _, pkg := range packages {
+		if pkg.Vulnerabilities == nil {
+			continue
+		}
+		for _, v := range pkg.Vulnerabilities.Vulnerabilities {
+			line := fmt.Sprintf("%s==%s: %s (%s)", pkg.Name, pkg.Vulnerabilities.Version, v.ID,
+				strings.Join(append([]string{v.Severity}, v.Aliases...), ", "))
+			if v.Upgrade != "" {
+				line += ", fixed in " + v.Upgrade
+			} else {
+				line += ", no fix yet"
+			}
+			lines = append(lines, line)
+			count++
+		}
+		if upgrade := pkg.Vulnerabilities.Upgrade; upgrade != "" && len(pkg.Vulnerabilities.Vulnerabilities) > 1 {
+			lines = append(lines, fmt.Sprintf("%s: upgrading to %s clears all of them", pkg.Name, upgrade))
+		}
+	}
+	return lines, count
+}
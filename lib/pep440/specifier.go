@@ -0,0 +1,235 @@
+package pep440
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var specifierOps = []string{"===", "==", "~=", "!=", "<=", ">=", "<", ">"}
+
+// Specifier is one version clause, like `>=1.0` or `==1.4.*`.
+type Specifier struct {
+	Op      string
+	Version string
+	// the parsed version, nil for `===` which compares strings, and for
+	// `==`/`!=` wildcards it is the prefix without the `.*`
+	parsed   *Version
+	wildcard bool
+}
+
+// ParseSpecifier reads a single clause and checks that the version is
+// valid for the operator it is used with.
+func ParseSpecifier(s string) (*Specifier, error) {
+	s = strings.TrimSpace(s)
+	var op string
+	for _, candidate := range specifierOps {
+		if strings.HasPrefix(s, candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return nil, fmt.Errorf("invalid version specifier: '%s'", s)
+	}
+	version := strings.TrimSpace(strings.TrimPrefix(s, op))
+	if version == "" {
+		return nil, fmt.Errorf("missing version in specifier: '%s'", s)
+	}
+	spec := &Specifier{Op: op, Version: version}
+
+	// arbitrary equality is just a string compare, anything goes
+	if op == "===" {
+		return spec, nil
+	}
+
+	if strings.HasSuffix(version, ".*") {
+		if op != "==" && op != "!=" {
+			return nil, fmt.Errorf("wildcards are only allowed with '==' and '!=': '%s'", s)
+		}
+		spec.wildcard = true
+		version = strings.TrimSuffix(version, ".*")
+	}
+
+	parsed, err := Parse(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version in specifier '%s'", s)
+	}
+	if len(parsed.Local) > 0 && op != "==" && op != "!=" {
+		return nil, fmt.Errorf("local versions are only allowed with '==' and '!=': '%s'", s)
+	}
+	// packaging only takes a plain release before the `.*`
+	if spec.wildcard && (parsed.Pre != nil || parsed.Post != nil || parsed.Dev != nil || len(parsed.Local) > 0) {
+		return nil, fmt.Errorf("wildcards can only follow a release like '1.4': '%s'", s)
+	}
+	if op == "~=" && len(parsed.Release) < 2 {
+		return nil, fmt.Errorf("'~=' needs at least two release segments: '%s'", s)
+	}
+	spec.parsed = parsed
+	return spec, nil
+}
+
+func (s *Specifier) String() string {
+	return s.Op + s.Version
+}
+
+// Prereleases says whether the specifier asks for pre-releases by
+// naming one itself, like `>=2.0b1`.
+func (s *Specifier) Prereleases() bool {
+	if s.Op == "!=" || s.parsed == nil {
+		return false
+	}
+	return s.parsed.IsPrerelease()
+}
+
+// Contains checks a version against the clause without any of the
+// pre-release filtering, that is done at the set level.
+func (s *Specifier) Contains(v *Version) bool {
+	switch s.Op {
+	case "===":
+		return strings.EqualFold(v.Original(), s.Version) || strings.EqualFold(v.String(), s.Version)
+	case "==":
+		return s.equal(v)
+	case "!=":
+		return !s.equal(v)
+	case "~=":
+		// ~=2.2.3 is >=2.2.3, ==2.2.*
+		prefix := &Version{Epoch: s.parsed.Epoch, Release: s.parsed.Release[:len(s.parsed.Release)-1]}
+		return v.Public().Compare(s.parsed) >= 0 && prefixMatch(v, prefix)
+	case "<=":
+		return v.Public().Compare(s.parsed) <= 0
+	case ">=":
+		return v.Public().Compare(s.parsed) >= 0
+	case "<":
+		if !v.LessThan(s.parsed) {
+			return false
+		}
+		// <3.0 shouldn't let in 3.0rc1, unless it was <3.0rc2 or such
+		if !s.parsed.IsPrerelease() && v.IsPrerelease() && v.Base().Equal(s.parsed.Base()) {
+			return false
+		}
+		return true
+	case ">":
+		if !v.GreaterThan(s.parsed) {
+			return false
+		}
+		// >1.0 shouldn't let in 1.0.post1 or 1.0+local
+		if !s.parsed.IsPostrelease() && v.IsPostrelease() && v.Base().Equal(s.parsed.Base()) {
+			return false
+		}
+		if len(v.Local) > 0 && v.Base().Equal(s.parsed.Base()) {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+func (s *Specifier) equal(v *Version) bool {
+	if s.wildcard {
+		return prefixMatch(v, s.parsed)
+	}
+	// a specifier without a local version matches any local version
+	if len(s.parsed.Local) == 0 {
+		return v.Public().Equal(s.parsed)
+	}
+	return v.Equal(s.parsed)
+}
+
+// prefixMatch handles the `==1.4.*` style compare. The candidate's
+// release gets zero padded so `1.4` matches `==1.4.0.*`.
+func prefixMatch(v, prefix *Version) bool {
+	if v.Epoch != prefix.Epoch {
+		return false
+	}
+	for i, n := range prefix.Release {
+		var got int
+		if i < len(v.Release) {
+			got = v.Release[i]
+		}
+		if got != n {
+			return false
+		}
+	}
+	return true
+}
+
+// SpecifierSet is a comma separated list of clauses that all have to
+// hold. The empty set matches everything.
+type SpecifierSet []*Specifier
+
+// ParseSpecifierSet reads `>=1.0,<2.0` style strings.
+func ParseSpecifierSet(s string) (SpecifierSet, error) {
+	set := SpecifierSet{}
+	if strings.TrimSpace(s) == "" {
+		return set, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		spec, err := ParseSpecifier(part)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, spec)
+	}
+	return set, nil
+}
+
+func (set SpecifierSet) String() string {
+	specs := []string{}
+	for _, spec := range set {
+		specs = append(specs, spec.String())
+	}
+	return strings.Join(specs, ",")
+}
+
+// Prereleases is true when any clause names a pre-release.
+func (set SpecifierSet) Prereleases() bool {
+	for _, spec := range set {
+		if spec.Prereleases() {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains checks a version against every clause. Pre-releases only
+// match if allowPre is set or one of the clauses names a pre-release.
+func (set SpecifierSet) Contains(v *Version, allowPre bool) bool {
+	if v.IsPrerelease() && !allowPre && !set.Prereleases() {
+		return false
+	}
+	for _, spec := range set {
+		if !spec.Contains(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter gives back the versions that match the set, in the order they
+// came in. Like pip, if only pre-releases match then those are used
+// rather than matching nothing.
+func (set SpecifierSet) Filter(versions []*Version, allowPre bool) []*Version {
+	matched := []*Version{}
+	prereleases := []*Version{}
+	for _, v := range versions {
+		if set.Contains(v, true) {
+			if v.IsPrerelease() && !allowPre && !set.Prereleases() {
+				prereleases = append(prereleases, v)
+			} else {
+				matched = append(matched, v)
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return prereleases
+	}
+	return matched
+}
+
+// Sort orders versions oldest to newest, in place.
+func Sort(versions []*Version) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].LessThan(versions[j])
+	})
+}
@@ -0,0 +1,120 @@
+package pep440
+
+import "testing"
+
+func TestSpecifierSetContains(t *testing.T) {
+	tests := []struct {
+		specs   string
+		version string
+		pre     bool
+		want    bool
+	}{
+		{">=1.0", "1.0", false, true},
+		{">=1.0", "0.9", false, false},
+		{"~=2.2", "2.3", false, true},
+		{"~=2.2", "3.0", false, false},
+		{"~=1.4.5", "1.4.9", false, true},
+		{"~=1.4.5", "1.5.0", false, false},
+		{"~=1.4.5", "1.4.4", false, false},
+		{"~=1.0", "1!1.0", false, false},
+
+		// wildcards
+		{"==1.4.*", "1.4.2", false, true},
+		{"==1.4.*", "1.4", false, true},
+		{"==1.4.*", "1.5", false, false},
+		{"==1.4.0.*", "1.4", false, true},
+		{"==1.4.*", "1.4.2+local", false, true},
+		{"!=1.4.*", "1.4.1", false, false},
+		{"!=1.4.*", "1.5", false, true},
+
+		// local versions
+		{"==1.0", "1.0+cu118", false, true},
+		{"==1.0+cu118", "1.0+cu118", false, true},
+		{"==1.0+cu118", "1.0", false, false},
+		{"!=1.0", "1.0+cu118", false, false},
+		{"<=2.0", "2.0+local", false, true},
+		{"===1.0", "1.0", false, true},
+		{"===1.0", "1.0.0", false, false},
+
+		// exclusive comparisons
+		{"<3.0", "3.0rc1", true, false},
+		{"<3.0rc2", "3.0rc1", false, true},
+		{"<3.0", "2.9", false, true},
+		{">1.0", "1.0.post1", false, false},
+		{">1.0.post0", "1.0.post1", false, true},
+		{">1.0", "1.0+local", false, false},
+		{">1.0", "1.1", false, true},
+
+		// pre-releases need asking for
+		{">=1.0", "2.0b1", false, false},
+		{">=1.0", "2.0b1", true, true},
+		{">=1.0b1", "2.0b1", false, true},
+		{">=1.0", "2.0.dev1", false, false},
+		{"", "2.0", false, true},
+
+		{">=1.0,<2.0,!=1.5", "1.5", false, false},
+		{">=1.0,<2.0,!=1.5", "1.6", false, true},
+		{">=1.0,<2.0,!=1.5", "2.0", false, false},
+	}
+	for _, test := range tests {
+		set, err := ParseSpecifierSet(test.specs)
+		if err != nil {
+			t.Errorf("ParseSpecifierSet(%q) error: %v", test.specs, err)
+			continue
+		}
+		if got := set.Contains(MustParse(test.version), test.pre); got != test.want {
+			t.Errorf("%q contains %s (pre %v) = %v, want %v", test.specs, test.version, test.pre, got, test.want)
+		}
+	}
+}
+
+func TestParseSpecifierErrors(t *testing.T) {
+	for _, bad := range []string{
+		"1.0",
+		"==",
+		">=1.0.*",
+		"~=1",
+		"<=1.0+local",
+		"==1.0+local.*",
+		"!=1.0+local.*",
+		"==1.0rc1.*",
+		"==1.0.post1.*",
+		"!=1.0.dev1.*",
+		"==not.a.version",
+	} {
+		if _, err := ParseSpecifier(bad); err == nil {
+			t.Errorf("ParseSpecifier(%q) should fail", bad)
+		}
+	}
+	if spec, err := ParseSpecifier("===anything goes"); err != nil || spec.Version != "anything goes" {
+		t.Errorf("ParseSpecifier(===) = %v, %v", spec, err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	versions := func(vs ...string) []*Version {
+		parsed := []*Version{}
+		for _, v := range vs {
+			parsed = append(parsed, MustParse(v))
+		}
+		return parsed
+	}
+	originals := func(vs []*Version) []string {
+		result := []string{}
+		for _, v := range vs {
+			result = append(result, v.Original())
+		}
+		return result
+	}
+	set, _ := ParseSpecifierSet(">=1.0")
+
+	got := originals(set.Filter(versions("0.9", "1.0", "1.1rc1", "1.1"), false))
+	if len(got) != 2 || got[0] != "1.0" || got[1] != "1.1" {
+		t.Errorf("Filter() = %v, want the final releases", got)
+	}
+	// nothing final matches, so pip falls back to the pre-releases
+	got = originals(set.Filter(versions("0.9", "1.1rc1"), false))
+	if len(got) != 1 || got[0] != "1.1rc1" {
+		t.Errorf("Filter() = %v, want the pre-release", got)
+	}
+}
@@ -0,0 +1,289 @@
+package pep440
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// this is the same pattern PyPA's `packaging` uses, it accepts all of
+// the alternate spellings PEP 440 allows and we normalize after
+var versionPattern = regexp.MustCompile(`(?i)^\s*v?` +
+	`(?:(?P<epoch>[0-9]+)!)?` +
+	`(?P<release>[0-9]+(?:\.[0-9]+)*)` +
+	`(?:[-_\.]?(?P<pre_l>alpha|a|beta|b|preview|pre|c|rc)[-_\.]?(?P<pre_n>[0-9]+)?)?` +
+	`(?:(?:-(?P<post_n1>[0-9]+))|(?:[-_\.]?(?P<post_l>post|rev|r)[-_\.]?(?P<post_n2>[0-9]+)?))?` +
+	`(?:[-_\.]?(?P<dev_l>dev)[-_\.]?(?P<dev_n>[0-9]+)?)?` +
+	`(?:\+(?P<local>[a-z0-9]+(?:[-_\.][a-z0-9]+)*))?` +
+	`\s*$`)
+
+// Version is a parsed PEP 440 version. Pre, Post and Dev are nil when
+// the version doesn't have that segment.
+type Version struct {
+	Epoch   int
+	Release []int
+	Pre     *PreRelease
+	Post    *int
+	Dev     *int
+	Local   []string
+	// the string the version was parsed from, kept for display
+	raw string
+}
+
+type PreRelease struct {
+	Label string // one of "a", "b" or "rc"
+	Num   int
+}
+
+// Parse reads a version string, normalizing the alternate spellings
+// (`1.0-RC1`, `1.0.post-2`, `v2` and so on) as it goes.
+func Parse(s string) (*Version, error) {
+	m := versionPattern.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("invalid version: '%s'", s)
+	}
+	group := func(name string) string {
+		return m[versionPattern.SubexpIndex(name)]
+	}
+
+	v := &Version{raw: s}
+	if e := group("epoch"); e != "" {
+		v.Epoch, _ = strconv.Atoi(e)
+	}
+	for _, part := range strings.Split(group("release"), ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version: '%s'", s)
+		}
+		v.Release = append(v.Release, n)
+	}
+
+	if label := strings.ToLower(group("pre_l")); label != "" {
+		switch label {
+		case "alpha":
+			label = "a"
+		case "beta":
+			label = "b"
+		case "c", "pre", "preview":
+			label = "rc"
+		}
+		v.Pre = &PreRelease{Label: label, Num: atoiOrZero(group("pre_n"))}
+	}
+
+	if n := group("post_n1"); n != "" {
+		post := atoiOrZero(n)
+		v.Post = &post
+	} else if group("post_l") != "" {
+		post := atoiOrZero(group("post_n2"))
+		v.Post = &post
+	}
+
+	if group("dev_l") != "" {
+		dev := atoiOrZero(group("dev_n"))
+		v.Dev = &dev
+	}
+
+	if local := group("local"); local != "" {
+		v.Local = strings.FieldsFunc(strings.ToLower(local), func(r rune) bool {
+			return r == '.' || r == '-' || r == '_'
+		})
+	}
+	return v, nil
+}
+
+// MustParse is Parse for versions known to be good, like constants.
+func MustParse(s string) *Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func atoiOrZero(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func (v *Version) IsPrerelease() bool {
+	return v.Pre != nil || v.Dev != nil
+}
+
+func (v *Version) IsPostrelease() bool {
+	return v.Post != nil
+}
+
+func (v *Version) IsDevrelease() bool {
+	return v.Dev != nil
+}
+
+// Original is the version exactly as it was written.
+func (v *Version) Original() string {
+	return v.raw
+}
+
+// Public is the version without its local segment.
+func (v *Version) Public() *Version {
+	public := *v
+	public.Local = nil
+	return &public
+}
+
+// Base is just the epoch and release, so `1.2rc1.post2` gives `1.2`.
+func (v *Version) Base() *Version {
+	return &Version{Epoch: v.Epoch, Release: v.Release}
+}
+
+// String gives the normalized form of the version.
+func (v *Version) String() string {
+	var sb strings.Builder
+	if v.Epoch != 0 {
+		fmt.Fprintf(&sb, "%d!", v.Epoch)
+	}
+	for i, n := range v.Release {
+		if i > 0 {
+			sb.WriteString(".")
+		}
+		sb.WriteString(strconv.Itoa(n))
+	}
+	if v.Pre != nil {
+		fmt.Fprintf(&sb, "%s%d", v.Pre.Label, v.Pre.Num)
+	}
+	if v.Post != nil {
+		fmt.Fprintf(&sb, ".post%d", *v.Post)
+	}
+	if v.Dev != nil {
+		fmt.Fprintf(&sb, ".dev%d", *v.Dev)
+	}
+	if len(v.Local) > 0 {
+		sb.WriteString("+" + strings.Join(v.Local, "."))
+	}
+	return sb.String()
+}
+
+// Compare gives -1, 0 or 1 following the PEP 440 total ordering.
+func (v *Version) Compare(other *Version) int {
+	if c := compareInt(v.Epoch, other.Epoch); c != 0 {
+		return c
+	}
+	if c := compareRelease(v.Release, other.Release); c != 0 {
+		return c
+	}
+	if c := compareInt(v.preKey(), other.preKey()); c != 0 {
+		return c
+	}
+	if c := compareInt(v.preNum(), other.preNum()); c != 0 {
+		return c
+	}
+	if c := compareOptional(v.Post, other.Post, -1); c != 0 {
+		return c
+	}
+	if c := compareOptional(v.Dev, other.Dev, 1); c != 0 {
+		return c
+	}
+	return compareLocal(v.Local, other.Local)
+}
+
+func (v *Version) Equal(other *Version) bool {
+	return v.Compare(other) == 0
+}
+
+func (v *Version) LessThan(other *Version) bool {
+	return v.Compare(other) < 0
+}
+
+func (v *Version) GreaterThan(other *Version) bool {
+	return v.Compare(other) > 0
+}
+
+// pre-releases sort before the final release, and a bare dev release
+// (`1.0.dev1`) sorts before any of the pre-releases of that version
+func (v *Version) preKey() int {
+	if v.Pre == nil {
+		if v.Post == nil && v.Dev != nil {
+			return -1
+		}
+		return 10
+	}
+	switch v.Pre.Label {
+	case "a":
+		return 1
+	case "b":
+		return 2
+	default:
+		return 3
+	}
+}
+
+func (v *Version) preNum() int {
+	if v.Pre == nil {
+		return 0
+	}
+	return v.Pre.Num
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// trailing zeros don't count, `1.0` and `1.0.0` are the same release
+func compareRelease(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if c := compareInt(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// missing says where a version without the segment sorts: -1 puts it
+// before any value (post releases), 1 after any value (dev releases)
+func compareOptional(a, b *int, missing int) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return missing
+	case b == nil:
+		return -missing
+	}
+	return compareInt(*a, *b)
+}
+
+// numeric local segments sort after alphanumeric ones, and a longer
+// local version wins when the shared segments are equal
+func compareLocal(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		x, xErr := strconv.Atoi(a[i])
+		y, yErr := strconv.Atoi(b[i])
+		switch {
+		case xErr == nil && yErr == nil:
+			if c := compareInt(x, y); c != 0 {
+				return c
+			}
+		case xErr == nil:
+			return 1
+		case yErr == nil:
+			return -1
+		default:
+			if c := strings.Compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareInt(len(a), len(b))
+}
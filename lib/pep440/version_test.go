@@ -0,0 +1,99 @@
+package pep440
+
+import "testing"
+
+func TestParseNormalizes(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"1.0", "1.0"},
+		{"v2", "2"},
+		{"1.0-RC1", "1.0rc1"},
+		{"1.0c1", "1.0rc1"},
+		{"1.0alpha1", "1.0a1"},
+		{"1.0.beta.2", "1.0b2"},
+		{"1.0-2", "1.0.post2"},
+		{"1.0-r4", "1.0.post4"},
+		{"1.0.post", "1.0.post0"},
+		{"1.0.dev", "1.0.dev0"},
+		{"1!2.0", "1!2.0"},
+		{"1.0+Ubuntu-1", "1.0+ubuntu.1"},
+		{" 2.0.0 ", "2.0.0"},
+	}
+	for _, test := range tests {
+		v, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", test.in, err)
+			continue
+		}
+		if got := v.String(); got != test.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", test.in, got, test.want)
+		}
+		if v.Original() != test.in {
+			t.Errorf("Parse(%q).Original() = %q", test.in, v.Original())
+		}
+	}
+
+	for _, bad := range []string{"", "1.0.x", "french toast", "1.0+", "1..0"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) should fail", bad)
+		}
+	}
+}
+
+func TestOrdering(t *testing.T) {
+	// from PEP 440's summary of permitted suffixes and relative ordering
+	ordered := []string{
+		"1.dev0",
+		"1.0.dev456",
+		"1.0a1",
+		"1.0a2.dev456",
+		"1.0a12.dev456",
+		"1.0a12",
+		"1.0b1.dev456",
+		"1.0b2",
+		"1.0b2.post345.dev456",
+		"1.0b2.post345",
+		"1.0rc1.dev456",
+		"1.0rc1",
+		"1.0",
+		"1.0+abc.5",
+		"1.0+abc.7",
+		"1.0+5",
+		"1.0.post456.dev34",
+		"1.0.post456",
+		"1.0.15",
+		"1.1.dev1",
+		"1!0.1",
+	}
+	for i := 0; i+1 < len(ordered); i++ {
+		a, b := MustParse(ordered[i]), MustParse(ordered[i+1])
+		if !a.LessThan(b) || !b.GreaterThan(a) || a.Compare(b) != -1 {
+			t.Errorf("%s should sort before %s", ordered[i], ordered[i+1])
+		}
+	}
+
+	equal := [][2]string{
+		{"1.0", "1.0.0"},
+		{"1.0rc1", "1.0c1"},
+		{"1.0.post0", "1.0-0"},
+		{"0!1.0", "1.0"},
+		{"1.0+ABC", "1.0+abc"},
+	}
+	for _, pair := range equal {
+		if !MustParse(pair[0]).Equal(MustParse(pair[1])) {
+			t.Errorf("%s should equal %s", pair[0], pair[1])
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	versions := []*Version{MustParse("2.0"), MustParse("1.0rc1"), MustParse("1.0.post1"), MustParse("1.0")}
+	Sort(versions)
+	want := []string{"1.0rc1", "1.0", "1.0.post1", "2.0"}
+	for i, v := range versions {
+		if v.Original() != want[i] {
+			t.Fatalf("Sort() = %v, want %v", versions, want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package pep508
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/pep440"
+)
+
+// Environment holds the values of the marker variables for one
+// interpreter and platform, like `"sys_platform": "linux"`. `extra` can
+// be left out, it's empty unless an extra is being installed.
+type Environment map[string]string
+
+func (env Environment) lookup(v MarkerValue) (string, error) {
+	if !v.IsVariable() {
+		return v.Literal, nil
+	}
+	value, ok := env[v.Variable]
+	if !ok {
+		if v.Variable == "extra" {
+			return "", nil
+		}
+		return "", fmt.Errorf("marker variable '%s' is not set for this environment", v.Variable)
+	}
+	return value, nil
+}
+
+// Evaluate says whether the marker holds in env. Both sides of an
+// `and` / `or` are always evaluated so an error anywhere in the marker
+// is reported.
+func (m *MarkerBool) Evaluate(env Environment) (bool, error) {
+	left, err := m.Left.Evaluate(env)
+	if err != nil {
+		return false, err
+	}
+	right, err := m.Right.Evaluate(env)
+	if err != nil {
+		return false, err
+	}
+	if m.Op == "and" {
+		return left && right, nil
+	}
+	return left || right, nil
+}
+
+// Evaluate compares the two sides. Like pip, versions are compared
+// with PEP 440 rules whenever both sides parse as versions, anything
+// else falls back to comparing the strings.
+func (m *MarkerExpr) Evaluate(env Environment) (bool, error) {
+	left, err := env.lookup(m.Left)
+	if err != nil {
+		return false, err
+	}
+	right, err := env.lookup(m.Right)
+	if err != nil {
+		return false, err
+	}
+	// extras are names, `Foo_Bar` and `foo-bar` are the same extra
+	if m.Left.Variable == "extra" || m.Right.Variable == "extra" {
+		left, right = NormalizeName(left), NormalizeName(right)
+	}
+
+	switch m.Op {
+	case "in":
+		return strings.Contains(right, left), nil
+	case "not in":
+		return !strings.Contains(right, left), nil
+	}
+
+	if spec, err := pep440.ParseSpecifier(m.Op + right); err == nil {
+		if version, err := pep440.Parse(left); err == nil {
+			return spec.Contains(version), nil
+		}
+	}
+
+	switch m.Op {
+	case "==", "===":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	}
+	return false, fmt.Errorf("cannot compare '%s' %s '%s', they are not versions", left, m.Op, right)
+}
+
+// Applies says whether the requirement should be installed in env. No
+// marker means it always is.
+func (r *Requirement) Applies(env Environment) (bool, error) {
+	if r.Marker == nil {
+		return true, nil
+	}
+	return r.Marker.Evaluate(env)
+}
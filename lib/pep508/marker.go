@@ -0,0 +1,79 @@
+package pep508
+
+import "strconv"
+
+// Marker is a node of an environment marker expression. It is either a
+// MarkerBool joining two sub-expressions or a MarkerExpr comparison.
+type Marker interface {
+	String() string
+	// Evaluate says whether the marker holds in the environment
+	Evaluate(env Environment) (bool, error)
+	isMarker()
+}
+
+// MarkerBool is an `and` / `or` of two marker expressions.
+type MarkerBool struct {
+	Op    string
+	Left  Marker
+	Right Marker
+}
+
+// MarkerExpr is a single comparison, like `python_version < "3.10"`.
+type MarkerExpr struct {
+	Left  MarkerValue
+	Op    string
+	Right MarkerValue
+}
+
+// MarkerValue is either an environment variable name or a quoted
+// string literal.
+type MarkerValue struct {
+	Variable string
+	Literal  string
+}
+
+func (v MarkerValue) IsVariable() bool {
+	return v.Variable != ""
+}
+
+func (v MarkerValue) String() string {
+	if v.IsVariable() {
+		return v.Variable
+	}
+	return strconv.Quote(v.Literal)
+}
+
+func (m *MarkerBool) String() string {
+	return wrapMarker(m.Left, m.Op) + " " + m.Op + " " + wrapMarker(m.Right, m.Op)
+}
+
+func (m *MarkerExpr) String() string {
+	return m.Left.String() + " " + m.Op + " " + m.Right.String()
+}
+
+// an `or` inside of an `and` needs its parens back to keep the meaning
+func wrapMarker(m Marker, parentOp string) string {
+	if b, ok := m.(*MarkerBool); ok && b.Op == "or" && parentOp == "and" {
+		return "(" + b.String() + ")"
+	}
+	return m.String()
+}
+
+func (*MarkerBool) isMarker() {}
+func (*MarkerExpr) isMarker() {}
+
+// the marker variables defined by PEP 508, `extra` included
+var markerVariables = map[string]bool{
+	"python_version":                 true,
+	"python_full_version":            true,
+	"os_name":                        true,
+	"sys_platform":                   true,
+	"platform_release":               true,
+	"platform_system":                true,
+	"platform_version":               true,
+	"platform_machine":               true,
+	"platform_python_implementation": true,
+	"implementation_name":            true,
+	"implementation_version":         true,
+	"extra":                          true,
+}
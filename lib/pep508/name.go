@@ -0,0 +1,16 @@
+package pep508
+
+import (
+	"regexp"
+	"strings"
+)
+
+var nameSeparatorRe = regexp.MustCompile(`[-_.]+`)
+
+// NormalizeName gives the PEP 503 canonical form of a project name,
+// lowercase with every run of `-`, `_` and `.` turned into a single
+// `-`. Two names are the same project exactly when their normalized
+// forms are equal, so `Typing_Extensions` is `typing-extensions`.
+func NormalizeName(name string) string {
+	return strings.ToLower(nameSeparatorRe.ReplaceAllString(name, "-"))
+}
@@ -0,0 +1,371 @@
+package pep508
+
+import (
+	"fmt"
+	"strings"
+)
+
+// the comparison operators, longest first so `===` wins over `==`
+var versionOps = []string{"===", "==", "~=", "!=", "<=", ">=", "<", ">"}
+
+type parser struct {
+	src string
+	pos int
+}
+
+// Parse reads a full PEP 508 dependency specification. Errors are
+// returned as *ParseError so callers can point at the offending column.
+func Parse(s string) (*Requirement, error) {
+	p := &parser{src: s}
+	req, err := p.requirement()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.done() {
+		return nil, p.errorf("unexpected '%s'", p.src[p.pos:])
+	}
+	return req, nil
+}
+
+// ParseMarker reads a marker expression on its own, without the
+// leading `;`.
+func ParseMarker(s string) (Marker, error) {
+	p := &parser{src: s}
+	m, err := p.markerOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.done() {
+		return nil, p.errorf("unexpected '%s' in marker", p.src[p.pos:])
+	}
+	return m, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) *ParseError {
+	return &ParseError{Input: p.src, Pos: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) skipSpace() bool {
+	start := p.pos
+	for !p.done() && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *parser) accept(tok string) bool {
+	if strings.HasPrefix(p.src[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+// keywords (`and`, `or`, `in`, `not`) have to stand on their own, so
+// `android` does not read as `and` + `roid`
+func (p *parser) acceptKeyword(word string) bool {
+	if !strings.HasPrefix(p.src[p.pos:], word) {
+		return false
+	}
+	end := p.pos + len(word)
+	if end < len(p.src) && isIdentChar(p.src[end]) {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func isAlnum(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func isIdentChar(c byte) bool {
+	return isAlnum(c) || c == '-' || c == '_' || c == '.'
+}
+
+func (p *parser) requirement() (*Requirement, error) {
+	p.skipSpace()
+	name, err := p.identifier("package name")
+	if err != nil {
+		return nil, err
+	}
+	req := &Requirement{Name: name}
+
+	p.skipSpace()
+	if p.peek() == '[' {
+		if req.Extras, err = p.extras(); err != nil {
+			return nil, err
+		}
+	}
+
+	p.skipSpace()
+	if p.accept("@") {
+		p.skipSpace()
+		if req.URL, err = p.url(); err != nil {
+			return nil, err
+		}
+		// the url eats everything up to whitespace, so a marker has to
+		// be separated from it by at least one space
+		hadSpace := p.skipSpace()
+		if p.peek() == ';' && !hadSpace {
+			return nil, p.errorf("expected whitespace between url and marker")
+		}
+	} else if req.Specifiers, err = p.versionSpec(); err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if p.accept(";") {
+		p.skipSpace()
+		if req.Marker, err = p.markerOr(); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+func (p *parser) identifier(what string) (string, error) {
+	start := p.pos
+	if !isAlnum(p.peek()) {
+		return "", p.errorf("expected %s", what)
+	}
+	for !p.done() && isIdentChar(p.peek()) {
+		p.pos++
+	}
+	// names must end on a letter or digit
+	if !isAlnum(p.src[p.pos-1]) {
+		p.pos--
+		return "", p.errorf("%s cannot end with '%c'", what, p.src[p.pos])
+	}
+	return p.src[start:p.pos], nil
+}
+
+func (p *parser) extras() ([]string, error) {
+	p.accept("[")
+	extras := []string{}
+	p.skipSpace()
+	if p.accept("]") {
+		return extras, nil
+	}
+	for {
+		p.skipSpace()
+		extra, err := p.identifier("extra name")
+		if err != nil {
+			return nil, err
+		}
+		extras = append(extras, extra)
+		p.skipSpace()
+		if p.accept("]") {
+			return extras, nil
+		}
+		if !p.accept(",") {
+			return nil, p.errorf("expected ',' or ']' after extra")
+		}
+	}
+}
+
+func (p *parser) url() (string, error) {
+	start := p.pos
+	for !p.done() && p.peek() != ' ' && p.peek() != '\t' {
+		p.pos++
+	}
+	url := p.src[start:p.pos]
+	if url == "" {
+		p.pos = start
+		return "", p.errorf("expected url after '@'")
+	}
+	if !strings.Contains(url, ":") {
+		p.pos = start
+		return "", p.errorf("invalid url '%s'", url)
+	}
+	return url, nil
+}
+
+func (p *parser) versionSpec() ([]Specifier, error) {
+	parens := p.accept("(")
+	specs := []Specifier{}
+	p.skipSpace()
+	if !p.startsVersionOp() {
+		if parens {
+			return nil, p.errorf("expected version specifier")
+		}
+		return specs, nil
+	}
+	for {
+		p.skipSpace()
+		spec, err := p.specifier()
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+		p.skipSpace()
+		if !p.accept(",") {
+			break
+		}
+	}
+	if parens && !p.accept(")") {
+		return nil, p.errorf("expected ')'")
+	}
+	return specs, nil
+}
+
+func (p *parser) startsVersionOp() bool {
+	for _, op := range versionOps {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) specifier() (Specifier, error) {
+	var op string
+	for _, candidate := range versionOps {
+		if p.accept(candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return Specifier{}, p.errorf("expected version operator")
+	}
+	p.skipSpace()
+	start := p.pos
+	for !p.done() && isVersionChar(p.peek()) {
+		p.pos++
+	}
+	if start == p.pos {
+		return Specifier{}, p.errorf("expected version after '%s'", op)
+	}
+	return Specifier{Op: op, Version: p.src[start:p.pos]}, nil
+}
+
+func isVersionChar(c byte) bool {
+	return isAlnum(c) || strings.IndexByte("-_.*+!", c) >= 0
+}
+
+func (p *parser) markerOr() (Marker, error) {
+	left, err := p.markerAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !p.acceptKeyword("or") {
+			return left, nil
+		}
+		right, err := p.markerAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &MarkerBool{Op: "or", Left: left, Right: right}
+	}
+}
+
+func (p *parser) markerAnd() (Marker, error) {
+	left, err := p.markerAtom()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !p.acceptKeyword("and") {
+			return left, nil
+		}
+		right, err := p.markerAtom()
+		if err != nil {
+			return nil, err
+		}
+		left = &MarkerBool{Op: "and", Left: left, Right: right}
+	}
+}
+
+func (p *parser) markerAtom() (Marker, error) {
+	p.skipSpace()
+	if p.accept("(") {
+		m, err := p.markerOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.accept(")") {
+			return nil, p.errorf("expected ')' in marker")
+		}
+		return m, nil
+	}
+
+	left, err := p.markerValue()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	op, err := p.markerOp()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	right, err := p.markerValue()
+	if err != nil {
+		return nil, err
+	}
+	return &MarkerExpr{Left: left, Op: op, Right: right}, nil
+}
+
+func (p *parser) markerOp() (string, error) {
+	for _, op := range versionOps {
+		if p.accept(op) {
+			return op, nil
+		}
+	}
+	if p.acceptKeyword("in") {
+		return "in", nil
+	}
+	if p.acceptKeyword("not") {
+		p.skipSpace()
+		if p.acceptKeyword("in") {
+			return "not in", nil
+		}
+		return "", p.errorf("expected 'in' after 'not'")
+	}
+	return "", p.errorf("expected marker operator")
+}
+
+func (p *parser) markerValue() (MarkerValue, error) {
+	quote := p.peek()
+	if quote == '"' || quote == '\'' {
+		start := p.pos
+		end := strings.IndexByte(p.src[p.pos+1:], quote)
+		if end < 0 {
+			return MarkerValue{}, p.errorf("unterminated string in marker")
+		}
+		p.pos += end + 2
+		return MarkerValue{Literal: p.src[start+1 : p.pos-1]}, nil
+	}
+
+	start := p.pos
+	for !p.done() && (isAlnum(p.peek()) || p.peek() == '_' || p.peek() == '.') {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	if name == "" {
+		return MarkerValue{}, p.errorf("expected marker variable or quoted string")
+	}
+	if !markerVariables[name] {
+		p.pos = start
+		return MarkerValue{}, p.errorf("unknown marker variable '%s'", name)
+	}
+	return MarkerValue{Variable: name}, nil
+}
@@ -0,0 +1,132 @@
+package pep508
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in     string
+		name   string
+		extras []string
+		specs  []string
+		url    string
+		// the requirement written back out
+		out string
+	}{
+		{in: "requests", name: "requests", out: "requests"},
+		{in: "requests >= 2.0 , < 3", name: "requests", specs: []string{">=2.0", "<3"}, out: "requests>=2.0,<3"},
+		{in: "requests(>=2.0)", name: "requests", specs: []string{">=2.0"}, out: "requests>=2.0"},
+		{in: "Flask[async, dotenv]==2.2.*", name: "Flask", extras: []string{"async", "dotenv"}, specs: []string{"==2.2.*"}, out: "Flask[async,dotenv]==2.2.*"},
+		{in: "numpy~=1.26;python_version<'3.13'", name: "numpy", specs: []string{"~=1.26"}, out: `numpy~=1.26; python_version < "3.13"`},
+		{in: "torch===2.1.0+cu118", name: "torch", specs: []string{"===2.1.0+cu118"}, out: "torch===2.1.0+cu118"},
+		{in: "pip @ https://example.com/pip.whl", name: "pip", url: "https://example.com/pip.whl", out: "pip @ https://example.com/pip.whl"},
+		{in: "pip @ file:///tmp/pip.whl ; os_name == 'nt'", name: "pip", url: "file:///tmp/pip.whl", out: `pip @ file:///tmp/pip.whl ; os_name == "nt"`},
+		{in: "zope.interface", name: "zope.interface", out: "zope.interface"},
+	}
+	for _, test := range tests {
+		req, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", test.in, err)
+			continue
+		}
+		if req.Name != test.name || !slices.Equal(req.Extras, test.extras) || !slices.Equal(req.SpecifierStrings(), append([]string{}, test.specs...)) || req.URL != test.url {
+			t.Errorf("Parse(%q) = %+v", test.in, req)
+		}
+		if got := req.String(); got != test.out {
+			t.Errorf("Parse(%q).String() = %q, want %q", test.in, got, test.out)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		pos int
+	}{
+		{"", 0},
+		{"-foo", 0},
+		{"foo[bar", 7},
+		{"foo >=", 6},
+		{"foo ==1.0 extra", 10},
+		{"foo; unknown_var == '1'", 5},
+		{"foo; python_version == '3", 23},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.in)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Parse(%q) error = %v, want a *ParseError", test.in, err)
+			continue
+		}
+		if parseErr.Pos != test.pos {
+			t.Errorf("Parse(%q) failed at %d (%s), want %d", test.in, parseErr.Pos, parseErr.Msg, test.pos)
+		}
+	}
+}
+
+func TestMarkers(t *testing.T) {
+	env := Environment{
+		"python_version":      "3.11",
+		"python_full_version": "3.11.4",
+		"sys_platform":        "linux",
+		"platform_machine":    "x86_64",
+		"os_name":             "posix",
+	}
+	tests := []struct {
+		marker string
+		env    Environment
+		want   bool
+	}{
+		{`python_version >= "3.8"`, env, true},
+		{`python_version < "3.10"`, env, false},
+		// compared as versions, not strings
+		{`python_version > "3.9"`, env, true},
+		{`python_full_version == "3.11.*"`, env, true},
+		{`sys_platform == "win32" or sys_platform == "linux"`, env, true},
+		{`sys_platform == "linux" and platform_machine == "arm64"`, env, false},
+		{`(os_name == "nt" or os_name == "posix") and python_version != "3.12"`, env, true},
+		{`"linux" in sys_platform`, env, true},
+		{`"x86" not in platform_machine`, env, false},
+		{`'3.11' == python_version`, env, true},
+		{`extra == "json"`, env, false},
+		{`extra == "JSON_Support"`, Environment{"extra": "json-support"}, true},
+	}
+	for _, test := range tests {
+		marker, err := ParseMarker(test.marker)
+		if err != nil {
+			t.Errorf("ParseMarker(%q) error: %v", test.marker, err)
+			continue
+		}
+		got, err := marker.Evaluate(test.env)
+		if err != nil || got != test.want {
+			t.Errorf("%q = %v, %v, want %v", test.marker, got, err, test.want)
+		}
+	}
+
+	// a variable the environment doesn't have is an error, not false
+	marker, _ := ParseMarker(`implementation_name == "cpython"`)
+	if _, err := marker.Evaluate(env); err == nil {
+		t.Errorf("evaluating a missing variable should fail")
+	}
+	marker, _ = ParseMarker(`sys_platform > "linux"`)
+	if _, err := marker.Evaluate(env); err == nil {
+		t.Errorf("ordering strings that aren't versions should fail")
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	tests := map[string]string{
+		"requests":          "requests",
+		"Typing_Extensions": "typing-extensions",
+		"zope.interface":    "zope-interface",
+		"Foo-._Bar":         "foo-bar",
+	}
+	for in, want := range tests {
+		if got := NormalizeName(in); got != want {
+			t.Errorf("NormalizeName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
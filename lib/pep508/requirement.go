@@ -0,0 +1,69 @@
+package pep508
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Requirement is the parsed form of a single PEP 508 dependency
+// specification, e.g. `name[extra1,extra2] >=1.0,<2.0 ; python_version < "3.10"`
+// or `name @ https://example.com/name.tar.gz`.
+type Requirement struct {
+	Name       string
+	Extras     []string
+	Specifiers []Specifier
+	URL        string
+	Marker     Marker
+}
+
+// Specifier is one clause of a version specifier, like `>=1.0`.
+type Specifier struct {
+	Op      string
+	Version string
+}
+
+func (s Specifier) String() string {
+	return s.Op + s.Version
+}
+
+// SpecifierStrings gives back each clause of the specifier set as it
+// would be written in a requirements file.
+func (r *Requirement) SpecifierStrings() []string {
+	specs := []string{}
+	for _, spec := range r.Specifiers {
+		specs = append(specs, spec.String())
+	}
+	return specs
+}
+
+func (r *Requirement) String() string {
+	var sb strings.Builder
+	sb.WriteString(r.Name)
+	if len(r.Extras) > 0 {
+		sb.WriteString("[" + strings.Join(r.Extras, ",") + "]")
+	}
+	if r.URL != "" {
+		sb.WriteString(" @ " + r.URL)
+		if r.Marker != nil {
+			// a space is required between the url and the marker
+			sb.WriteString(" ")
+		}
+	} else {
+		sb.WriteString(strings.Join(r.SpecifierStrings(), ","))
+	}
+	if r.Marker != nil {
+		sb.WriteString("; " + r.Marker.String())
+	}
+	return sb.String()
+}
+
+// ParseError points at the byte offset in the input where parsing gave up.
+type ParseError struct {
+	Input string
+	Pos   int
+	Msg   string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s at column %d in '%s'", e.Msg, e.Pos+1, e.Input)
+}
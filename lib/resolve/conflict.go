@@ -0,0 +1,78 @@
+package resolve
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+)
+
+// Cause is one requirement on a package along with how the resolver got
+// there, so a conflict can be explained.
+type Cause struct {
+	// what was asked for, like `urllib3<1.27,>=1.25.4`
+	Requirement string `json:"requirement"`
+	// the path from the file down to the requirement, like
+	// `["requirements.txt", "boto3==1.26.0", "botocore==1.29.0"]`
+	Chain []string `json:"chain"`
+	// the top level requirement the chain starts at, nil for constraints
+	Root *pep508.Requirement `json:"-"`
+}
+
+func (c Cause) String() string {
+	return strings.Join(append(append([]string{}, c.Chain...), c.Requirement), " -> ")
+}
+
+// Conflict is what Resolve gives back when no set of versions satisfies
+// every requirement. It only names the package it got stuck on and the
+// requirements on it, not everything the search tried on the way, and
+// it's worked out from the fewest top level requirements that still
+// fail.
+type Conflict struct {
+	Name string `json:"name"`
+	// the index has no versions of the package at all
+	NotFound bool    `json:"not_found,omitempty"`
+	Causes   []Cause `json:"causes"`
+	// the smallest set of top level requirements that can't be
+	// installed together, like `["boto3==1.26.0", "requests>=2.31"]`
+	Requirements []string `json:"requirements"`
+
+	roots []*pep508.Requirement
+}
+
+func (c *Conflict) Error() string {
+	var sb strings.Builder
+	if c.NotFound {
+		fmt.Fprintf(&sb, "package '%s' was not found on the index, it is required by:", c.Name)
+	} else {
+		fmt.Fprintf(&sb, "no version of '%s' satisfies every requirement on it:", c.Name)
+	}
+	for _, cause := range c.Causes {
+		sb.WriteString("\n    " + cause.String())
+	}
+	if len(c.Requirements) > 1 {
+		fmt.Fprintf(&sb, "\nthese requirements can't be installed together: %s", strings.Join(c.Requirements, ", "))
+	}
+	return sb.String()
+}
+
+// Roots are the top level requirements the conflict comes from, the
+// lines to point at in the file.
+func (c *Conflict) Roots() []*pep508.Requirement {
+	return c.roots
+}
+
+// describe is the version part of a requirement, for explanations
+func describe(req *pep508.Requirement) string {
+	name := req.Name
+	if len(req.Extras) > 0 {
+		name += "[" + strings.Join(req.Extras, ",") + "]"
+	}
+	switch {
+	case req.URL != "":
+		return name + " @ " + req.URL
+	case len(req.Specifiers) == 0:
+		return name + " (any version)"
+	}
+	return name + strings.Join(req.SpecifierStrings(), ",")
+}
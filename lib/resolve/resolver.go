@@ -0,0 +1,611 @@
+package resolve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	"github.com/DerekCorniello/pip-req-valid/pep440"
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+)
+
+// ErrTooComplex means the search gave up before finding an answer
+// either way, see Resolver.MaxRounds.
+var ErrTooComplex = errors.New("resolution is too complex")
+
+const DefaultMaxRounds = 2000
+
+// Root is a requirement asked for directly, Source says where from,
+// like `requirements.txt`.
+type Root struct {
+	Requirement *pep508.Requirement
+	Source      string
+}
+
+// Pin is one package of a resolution and the version picked for it.
+type Pin struct {
+	// the PEP 503 normalized name
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	// set instead of Version for direct references
+	URL    string   `json:"url,omitempty"`
+	Extras []string `json:"extras,omitempty"`
+	// the files that ask for the package directly
+	Sources []string `json:"sources,omitempty"`
+	// the other pinned packages that depend on it
+	RequiredBy []string       `json:"required_by,omitempty"`
+	Release    *index.Release `json:"-"`
+
+	// how the resolver first got to the package, for explanations
+	chain []string
+	root  *pep508.Requirement
+}
+
+func (p *Pin) String() string {
+	if p.URL != "" {
+		return p.Name + " @ " + p.URL
+	}
+	return p.Name + "==" + p.Version
+}
+
+// Resolution is a consistent set of pins, sorted by name.
+type Resolution struct {
+	Pins []*Pin `json:"pins"`
+}
+
+// Resolver picks a version of every package the roots need, directly
+// or through `Requires-Dist`, so that all of the requirements hold at
+// once. It tries the newest versions first and backtracks when it runs
+// into a conflict, like pip does. A Resolver caches what it reads from
+// the index, so reuse it to resolve for several environments.
+type Resolver struct {
+	Index index.PackageIndex
+	// allow pre-releases, like --pre
+	Pre bool
+	// limit versions without asking for anything to be installed, like
+	// requirements from `-c` files
+	Constraints []Root
+	// how many candidate versions to try before giving up, zero means
+	// DefaultMaxRounds
+	MaxRounds int
+
+	mu       sync.Mutex
+	versions map[string][]*pep440.Version
+	// by "name==version"
+	yanked         map[string]bool
+	requiresPython map[string]string
+	releases       map[string]*index.Release
+}
+
+// criterion is one requirement on a package and where it came from
+type criterion struct {
+	req   *pep508.Requirement
+	chain []string
+	root  *pep508.Requirement
+	// the normalized name of the pin that asked for it, empty for roots
+	parent string
+	source string
+}
+
+func (c criterion) cause() Cause {
+	return Cause{Requirement: describe(c.req), Chain: c.chain, Root: c.root}
+}
+
+// state is one branch of the search. Every candidate gets its own copy
+// so backtracking is just dropping it.
+type state struct {
+	pins     map[string]*Pin
+	criteria map[string][]criterion
+	// names in the order they were first asked for
+	order []string
+}
+
+func (st *state) clone() *state {
+	next := &state{
+		pins:     map[string]*Pin{},
+		criteria: map[string][]criterion{},
+		order:    slices.Clone(st.order),
+	}
+	for name, pin := range st.pins {
+		copied := *pin
+		copied.Extras = slices.Clone(pin.Extras)
+		next.pins[name] = &copied
+	}
+	for name, criteria := range st.criteria {
+		// clipped so appends on the copy never write into ours
+		next.criteria[name] = slices.Clip(criteria)
+	}
+	return next
+}
+
+// run is the state of a single Resolve call
+type run struct {
+	*Resolver
+	ctx    context.Context
+	env    pep508.Environment
+	rounds int
+	// the first conflict hit, which is the one worth explaining since
+	// it was found with the newest versions
+	conflict *Conflict
+}
+
+// Resolve finds versions for the roots whose markers hold in env. The
+// error is a *Conflict when there is no consistent set of versions.
+func (r *Resolver) Resolve(ctx context.Context, roots []Root, env pep508.Environment) (*Resolution, error) {
+	resolution, err := r.resolve(ctx, roots, env)
+	var conflict *Conflict
+	if errors.As(err, &conflict) {
+		return nil, r.minimize(ctx, roots, env, conflict)
+	}
+	return resolution, err
+}
+
+// minimize drops the roots a conflict doesn't need, one at a time,
+// keeping each one the rest resolve without. What's left is a smallest
+// set of requirements that can't be installed together, and the
+// conflict it runs into is the one explained.
+func (r *Resolver) minimize(ctx context.Context, roots []Root, env pep508.Environment, conflict *Conflict) *Conflict {
+	needed := slices.Clone(roots)
+	for i := 0; i < len(needed); {
+		without := slices.Delete(slices.Clone(needed), i, i+1)
+		_, err := r.resolve(ctx, without, env)
+		var smaller *Conflict
+		if errors.As(err, &smaller) {
+			needed, conflict = without, smaller
+			continue
+		}
+		i++
+	}
+	conflict.roots = nil
+	conflict.Requirements = []string{}
+	for _, root := range needed {
+		conflict.roots = append(conflict.roots, root.Requirement)
+		conflict.Requirements = append(conflict.Requirements, root.Requirement.String())
+	}
+	return conflict
+}
+
+func (r *Resolver) resolve(ctx context.Context, roots []Root, env pep508.Environment) (*Resolution, error) {
+	run := &run{Resolver: r, ctx: ctx, env: env}
+	st := &state{pins: map[string]*Pin{}, criteria: map[string][]criterion{}}
+	for _, root := range roots {
+		if applies, err := root.Requirement.Applies(env); err != nil || !applies {
+			continue
+		}
+		c := criterion{req: root.Requirement, chain: []string{root.Source}, root: root.Requirement, source: root.Source}
+		if conflict := run.add(st, c); conflict != nil {
+			return nil, conflict
+		}
+	}
+
+	final, err := run.solve(st)
+	if err != nil {
+		var conflict *Conflict
+		if errors.As(err, &conflict) && run.conflict != nil {
+			return nil, run.conflict
+		}
+		return nil, err
+	}
+	return final.resolution(), nil
+}
+
+func (st *state) resolution() *Resolution {
+	resolution := &Resolution{Pins: []*Pin{}}
+	for name, pin := range st.pins {
+		for _, c := range st.criteria[name] {
+			if c.parent != "" && !slices.Contains(pin.RequiredBy, c.parent) {
+				pin.RequiredBy = append(pin.RequiredBy, c.parent)
+			} else if c.parent == "" && !slices.Contains(pin.Sources, c.source) {
+				pin.Sources = append(pin.Sources, c.source)
+			}
+		}
+		sort.Strings(pin.RequiredBy)
+		resolution.Pins = append(resolution.Pins, pin)
+	}
+	sort.Slice(resolution.Pins, func(i, j int) bool {
+		return resolution.Pins[i].Name < resolution.Pins[j].Name
+	})
+	return resolution
+}
+
+func (run *run) record(conflict *Conflict) {
+	if run.conflict == nil {
+		run.conflict = conflict
+	}
+}
+
+func (run *run) conflictFor(st *state, name string, notFound bool) *Conflict {
+	conflict := &Conflict{Name: name, NotFound: notFound}
+	for _, c := range st.criteria[name] {
+		conflict.Causes = append(conflict.Causes, c.cause())
+	}
+	for _, c := range run.constraints(name) {
+		conflict.Causes = append(conflict.Causes, c.cause())
+	}
+	return conflict
+}
+
+func (run *run) constraints(name string) []criterion {
+	criteria := []criterion{}
+	for _, constraint := range run.Constraints {
+		if pep508.NormalizeName(constraint.Requirement.Name) == name {
+			criteria = append(criteria, criterion{req: constraint.Requirement, chain: []string{constraint.Source}, source: constraint.Source})
+		}
+	}
+	return criteria
+}
+
+// add records a new requirement. If the package is already pinned the
+// pin has to satisfy it, and any new extras bring in their
+// dependencies.
+func (run *run) add(st *state, c criterion) *Conflict {
+	name := pep508.NormalizeName(c.req.Name)
+	if _, ok := st.criteria[name]; !ok {
+		st.order = append(st.order, name)
+	}
+	st.criteria[name] = append(st.criteria[name], c)
+
+	pin, ok := st.pins[name]
+	if !ok {
+		return nil
+	}
+	if !satisfies(pin, c.req) {
+		return run.conflictFor(st, name, false)
+	}
+	extras := []string{}
+	for _, extra := range c.req.Extras {
+		extra = pep508.NormalizeName(extra)
+		if !slices.Contains(pin.Extras, extra) {
+			pin.Extras = append(pin.Extras, extra)
+			extras = append(extras, extra)
+		}
+	}
+	return run.addDependencies(st, pin, extras)
+}
+
+// addDependencies adds the requirements of a pinned release, "" in
+// extras stands for the ones that don't need any extra.
+func (run *run) addDependencies(st *state, pin *Pin, extras []string) *Conflict {
+	if pin.Release == nil {
+		return nil
+	}
+	for _, dist := range pin.Release.RequiresDist {
+		dep, err := pep508.Parse(dist)
+		if err != nil {
+			// broken metadata, pip skips these too
+			continue
+		}
+		if !run.wanted(dep, extras) {
+			continue
+		}
+		c := criterion{req: dep, chain: pin.chain, root: pin.root, parent: pin.Name}
+		if conflict := run.add(st, c); conflict != nil {
+			return conflict
+		}
+	}
+	return nil
+}
+
+// wanted says whether a dependency is needed for any of the extras. A
+// dependency only counts for an extra if it wasn't already needed
+// without it, so it isn't added twice.
+func (run *run) wanted(dep *pep508.Requirement, extras []string) bool {
+	base, err := dep.Applies(withExtra(run.env, ""))
+	if err != nil {
+		return false
+	}
+	for _, extra := range extras {
+		if extra == "" {
+			if base {
+				return true
+			}
+			continue
+		}
+		applies, err := dep.Applies(withExtra(run.env, extra))
+		if err == nil && applies && !base {
+			return true
+		}
+	}
+	return false
+}
+
+func withExtra(env pep508.Environment, extra string) pep508.Environment {
+	withExtra := pep508.Environment{}
+	for k, v := range env {
+		withExtra[k] = v
+	}
+	withExtra["extra"] = extra
+	return withExtra
+}
+
+func specifiers(req *pep508.Requirement) pep440.SpecifierSet {
+	set, err := pep440.ParseSpecifierSet(strings.Join(req.SpecifierStrings(), ","))
+	if err != nil {
+		// a specifier we can't read doesn't rule anything out
+		return pep440.SpecifierSet{}
+	}
+	return set
+}
+
+func satisfies(pin *Pin, req *pep508.Requirement) bool {
+	if req.URL != "" || pin.URL != "" {
+		// nothing to compare a url pin's version with, only another
+		// url can disagree with it
+		return req.URL == "" || req.URL == pin.URL
+	}
+	version, err := pep440.Parse(pin.Version)
+	if err != nil {
+		return false
+	}
+	return specifiers(req).Contains(version, true)
+}
+
+// next picks the package to pin next. Exact pins go first since they
+// have only the one candidate, then everything in the order it turned
+// up.
+func (run *run) next(st *state) string {
+	first := ""
+	for _, name := range st.order {
+		if _, pinned := st.pins[name]; pinned {
+			continue
+		}
+		for _, c := range st.criteria[name] {
+			if c.req.URL != "" || (len(c.req.Specifiers) == 1 && c.req.Specifiers[0].Op == "==") {
+				return name
+			}
+		}
+		if first == "" {
+			first = name
+		}
+	}
+	return first
+}
+
+func (run *run) solve(st *state) (*state, error) {
+	if err := run.ctx.Err(); err != nil {
+		return nil, err
+	}
+	name := run.next(st)
+	if name == "" {
+		return st, nil
+	}
+	criteria := st.criteria[name]
+
+	// direct references are taken as they are, there's no version to
+	// choose and no metadata to read without downloading them
+	for _, c := range criteria {
+		if c.req.URL == "" {
+			continue
+		}
+		next := st.clone()
+		next.pins[name] = &Pin{Name: name, URL: c.req.URL, chain: append(slices.Clone(c.chain), name+" @ "+c.req.URL), root: c.root}
+		for _, other := range criteria {
+			if !satisfies(next.pins[name], other.req) {
+				conflict := run.conflictFor(st, name, false)
+				run.record(conflict)
+				return nil, conflict
+			}
+		}
+		return run.solve(next)
+	}
+
+	candidates, err := run.candidates(st, name)
+	if errors.Is(err, index.ErrNotFound) {
+		conflict := run.conflictFor(st, name, true)
+		run.record(conflict)
+		return nil, conflict
+	} else if err != nil {
+		return nil, err
+	}
+
+	extras := []string{}
+	for _, c := range criteria {
+		for _, extra := range c.req.Extras {
+			if extra = pep508.NormalizeName(extra); !slices.Contains(extras, extra) {
+				extras = append(extras, extra)
+			}
+		}
+	}
+	sort.Strings(extras)
+
+	// a release whose metadata can't be read is passed over, it's only
+	// an error when that's what every candidate came to
+	var metadataErr error
+	tried, unreadable := 0, 0
+	for _, version := range candidates {
+		// pip passes over yanked releases unless they're pinned exactly,
+		// and releases that don't support the interpreter
+		if run.isYanked(name, version) && !pinnedExactly(criteria) {
+			continue
+		}
+		if !run.supportsPython(run.requiresPythonOf(name, version)) {
+			continue
+		}
+		run.rounds++
+		if run.rounds > run.maxRounds() {
+			return nil, fmt.Errorf("%w, gave up after trying %d versions", ErrTooComplex, run.maxRounds())
+		}
+		tried++
+		release, err := run.release(name, version.Original())
+		if err != nil {
+			metadataErr = err
+			unreadable++
+			continue
+		}
+		// the metadata can be stricter than the index listing
+		if !run.supportsPython(release.RequiresPython) {
+			continue
+		}
+
+		next := st.clone()
+		pin := &Pin{
+			Name:    name,
+			Version: version.Original(),
+			Extras:  extras,
+			Release: release,
+			chain:   append(slices.Clone(criteria[0].chain), name+"=="+version.Original()),
+			root:    criteria[0].root,
+		}
+		next.pins[name] = pin
+		if conflict := run.addDependencies(next, pin, append([]string{""}, extras...)); conflict != nil {
+			run.record(conflict)
+			continue
+		}
+
+		final, err := run.solve(next)
+		if err == nil {
+			return final, nil
+		}
+		var conflict *Conflict
+		if !errors.As(err, &conflict) {
+			return nil, err
+		}
+	}
+	if tried > 0 && unreadable == tried {
+		return nil, metadataErr
+	}
+
+	conflict := run.conflictFor(st, name, false)
+	run.record(conflict)
+	return nil, conflict
+}
+
+func pinnedExactly(criteria []criterion) bool {
+	for _, c := range criteria {
+		for _, spec := range c.req.Specifiers {
+			if (spec.Op == "==" || spec.Op == "===") && !strings.HasSuffix(spec.Version, ".*") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// candidates are the versions allowed by every requirement on the
+// package so far, newest first.
+func (run *run) candidates(st *state, name string) ([]*pep440.Version, error) {
+	versions, err := run.versionsOf(name)
+	if err != nil {
+		return nil, err
+	}
+	set := pep440.SpecifierSet{}
+	for _, c := range append(slices.Clone(st.criteria[name]), run.constraints(name)...) {
+		set = append(set, specifiers(c.req)...)
+	}
+	return set.Filter(versions, run.Pre), nil
+}
+
+func (r *Resolver) maxRounds() int {
+	if r.MaxRounds <= 0 {
+		return DefaultMaxRounds
+	}
+	return r.MaxRounds
+}
+
+func (run *run) versionsOf(name string) ([]*pep440.Version, error) {
+	run.mu.Lock()
+	cached, ok := run.versions[name]
+	run.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	raw, err := run.Index.Versions(run.ctx, name)
+	// go by the indexes that answered, like pip does, verification
+	// reports the ones that didn't
+	var partial *index.PartialError
+	if err != nil && !errors.As(err, &partial) {
+		return nil, err
+	}
+	versions := []*pep440.Version{}
+	yanked := map[string]bool{}
+	requiresPython := map[string]string{}
+	for _, version := range raw {
+		// legacy versions pip would skip too
+		if v, err := pep440.Parse(version.Version); err == nil {
+			versions = append(versions, v)
+			yanked[name+"=="+version.Version] = version.Yanked
+			requiresPython[name+"=="+version.Version] = version.RequiresPython
+		}
+	}
+	pep440.Sort(versions)
+	slices.Reverse(versions)
+
+	run.mu.Lock()
+	if run.versions == nil {
+		run.versions = map[string][]*pep440.Version{}
+		run.yanked = map[string]bool{}
+		run.requiresPython = map[string]string{}
+	}
+	run.versions[name] = versions
+	for key, isYanked := range yanked {
+		run.yanked[key] = isYanked
+	}
+	for key, value := range requiresPython {
+		run.requiresPython[key] = value
+	}
+	run.mu.Unlock()
+	return versions, nil
+}
+
+func (run *run) isYanked(name string, version *pep440.Version) bool {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	return run.yanked[name+"=="+version.Original()]
+}
+
+func (run *run) requiresPythonOf(name string, version *pep440.Version) string {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	return run.requiresPython[name+"=="+version.Original()]
+}
+
+// supportsPython checks a Requires-Python value against the interpreter
+// of the environment. Like pip, a value that can't be parsed doesn't
+// rule anything out, and neither does an environment without a python.
+func (run *run) supportsPython(requiresPython string) bool {
+	if requiresPython == "" {
+		return true
+	}
+	python := run.env["python_full_version"]
+	if python == "" {
+		python = run.env["python_version"]
+	}
+	version, err := pep440.Parse(python)
+	if err != nil {
+		return true
+	}
+	set, err := pep440.ParseSpecifierSet(strings.ReplaceAll(requiresPython, " ", ""))
+	if err != nil {
+		return true
+	}
+	return set.Contains(version, true)
+}
+
+func (run *run) release(name string, version string) (*index.Release, error) {
+	key := name + "==" + version
+	run.mu.Lock()
+	cached, ok := run.releases[key]
+	run.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	release, err := run.Index.Release(run.ctx, name, version)
+	if err != nil {
+		return nil, fmt.Errorf("error reading metadata of %s: %w", key, err)
+	}
+
+	run.mu.Lock()
+	if run.releases == nil {
+		run.releases = map[string]*index.Release{}
+	}
+	run.releases[key] = release
+	run.mu.Unlock()
+	return release, nil
+}
@@ -0,0 +1,221 @@
+package resolve
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+)
+
+// release is a version of a package with its dependencies and
+// Requires-Python
+type release struct {
+	version        string
+	requires       []string
+	requiresPython string
+}
+
+func testIndex(projects map[string][]release) index.Memory {
+	mem := index.Memory{}
+	for name, releases := range projects {
+		project := &index.Project{Name: name, Releases: map[string]*index.ProjectRelease{}}
+		for _, r := range releases {
+			project.Releases[r.version] = &index.ProjectRelease{Release: index.Release{
+				Name: name, Version: r.version, RequiresDist: r.requires, RequiresPython: r.requiresPython,
+			}}
+		}
+		mem[name] = project
+	}
+	return mem
+}
+
+func roots(t *testing.T, reqs ...string) []Root {
+	t.Helper()
+	result := []Root{}
+	for _, req := range reqs {
+		parsed, err := pep508.Parse(req)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", req, err)
+		}
+		result = append(result, Root{Requirement: parsed, Source: "requirements.txt"})
+	}
+	return result
+}
+
+func py(version string) pep508.Environment {
+	return pep508.Environment{"python_version": version[:4], "python_full_version": version, "sys_platform": "linux"}
+}
+
+// pins gives name==version for every pin, urls as name @ url
+func pins(resolution *Resolution) map[string]string {
+	result := map[string]string{}
+	for _, pin := range resolution.Pins {
+		result[pin.Name] = pin.String()
+	}
+	return result
+}
+
+func TestResolve(t *testing.T) {
+	idx := testIndex(map[string][]release{
+		"app": {
+			{version: "2.0", requires: []string{"lib>=2"}},
+			{version: "1.0", requires: []string{"lib<2"}},
+		},
+		"lib": {{version: "1.5"}, {version: "2.1"}},
+		"other": {
+			{version: "1.0", requires: []string{"lib<2"}},
+		},
+		"web": {
+			{version: "1.0", requires: []string{"lib", "json-extra; extra == 'json'", "winonly; sys_platform == 'win32'"}},
+		},
+		"json-extra": {{version: "0.3"}},
+		"winonly":    {{version: "1.0"}},
+		"numpy": {
+			{version: "1.24.4", requiresPython: ">=3.8"},
+			{version: "2.0.0", requiresPython: ">=3.9"},
+		},
+	})
+
+	tests := []struct {
+		name  string
+		roots []string
+		env   pep508.Environment
+		want  map[string]string
+	}{
+		{
+			name:  "newest versions",
+			roots: []string{"app"},
+			env:   py("3.12.1"),
+			want:  map[string]string{"app": "app==2.0", "lib": "lib==2.1"},
+		},
+		{
+			// app 2.0 needs lib>=2 but other needs lib<2, so it has to
+			// back off to app 1.0
+			name:  "backtracking",
+			roots: []string{"app", "other"},
+			env:   py("3.12.1"),
+			want:  map[string]string{"app": "app==1.0", "lib": "lib==1.5", "other": "other==1.0"},
+		},
+		{
+			name:  "extras",
+			roots: []string{"web[json]"},
+			env:   py("3.12.1"),
+			want:  map[string]string{"web": "web==1.0", "lib": "lib==2.1", "json-extra": "json-extra==0.3"},
+		},
+		{
+			name:  "markers leave out other platforms",
+			roots: []string{"web", "numpy; python_version < '3.0'"},
+			env:   py("3.12.1"),
+			want:  map[string]string{"web": "web==1.0", "lib": "lib==2.1"},
+		},
+		{
+			name:  "requires python",
+			roots: []string{"numpy"},
+			env:   py("3.8.18"),
+			want:  map[string]string{"numpy": "numpy==1.24.4"},
+		},
+		{
+			name:  "requires python allows the newest",
+			roots: []string{"numpy"},
+			env:   py("3.12.1"),
+			want:  map[string]string{"numpy": "numpy==2.0.0"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resolver := &Resolver{Index: idx}
+			resolution, err := resolver.Resolve(context.Background(), roots(t, test.roots...), test.env)
+			if err != nil {
+				t.Fatalf("Resolve() error: %v", err)
+			}
+			got := pins(resolution)
+			if len(got) != len(test.want) {
+				t.Fatalf("Resolve() = %v, want %v", got, test.want)
+			}
+			for name, pin := range test.want {
+				if got[name] != pin {
+					t.Errorf("Resolve() = %v, want %v", got, test.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestResolveConflict(t *testing.T) {
+	idx := testIndex(map[string][]release{
+		"a":   {{version: "1.0", requires: []string{"lib>=2"}}},
+		"b":   {{version: "1.0", requires: []string{"lib<2"}}},
+		"x":   {{version: "1.0", requires: []string{"lib"}}},
+		"lib": {{version: "1.0"}, {version: "2.0"}},
+		"new": {{version: "1.0", requiresPython: ">=3.10"}},
+	})
+
+	resolver := &Resolver{Index: idx}
+	_, err := resolver.Resolve(context.Background(), roots(t, "a", "b"), py("3.12.1"))
+	var conflict *Conflict
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Resolve() error = %v, want a conflict", err)
+	}
+	if conflict.Name != "lib" || len(conflict.Causes) != 2 {
+		t.Errorf("conflict = %+v, want both requirements on lib", conflict)
+	}
+
+	// x asks for lib too, but a and b are enough to fail
+	_, err = resolver.Resolve(context.Background(), roots(t, "x", "a", "b", "new"), py("3.12.1"))
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Resolve() error = %v, want a conflict", err)
+	}
+	if len(conflict.Causes) != 2 || !slices.Equal(conflict.Requirements, []string{"a", "b"}) {
+		t.Errorf("conflict = %+v, want it narrowed down to a and b", conflict)
+	}
+
+	_, err = resolver.Resolve(context.Background(), roots(t, "missing"), py("3.12.1"))
+	if !errors.As(err, &conflict) || !conflict.NotFound {
+		t.Errorf("Resolve() error = %v, want a not found conflict", err)
+	}
+
+	// nothing supports the interpreter
+	_, err = resolver.Resolve(context.Background(), roots(t, "new"), py("3.8.18"))
+	if !errors.As(err, &conflict) || conflict.Name != "new" {
+		t.Errorf("Resolve() error = %v, want a conflict on new", err)
+	}
+}
+
+// brokenIndex can't read the metadata of some releases
+type brokenIndex struct {
+	index.Memory
+	broken map[string]bool
+}
+
+func (b brokenIndex) Release(ctx context.Context, name string, version string) (*index.Release, error) {
+	if b.broken[name+"=="+version] {
+		return nil, errors.New("metadata unavailable")
+	}
+	return b.Memory.Release(ctx, name, version)
+}
+
+func TestResolveSkipsUnreadableMetadata(t *testing.T) {
+	idx := brokenIndex{
+		Memory: testIndex(map[string][]release{"lib": {{version: "1.0"}, {version: "2.0"}}}),
+		broken: map[string]bool{"lib==2.0": true},
+	}
+	resolution, err := (&Resolver{Index: idx}).Resolve(context.Background(), roots(t, "lib"), py("3.12.1"))
+	if err != nil {
+		t.Fatalf("Resolve() error: %v", err)
+	}
+	if got := pins(resolution)["lib"]; got != "lib==1.0" {
+		t.Errorf("Resolve() pinned %s, want lib==1.0", got)
+	}
+
+	// it's still an error when nothing can be read
+	idx.broken["lib==1.0"] = true
+	_, err = (&Resolver{Index: idx}).Resolve(context.Background(), roots(t, "lib"), py("3.12.1"))
+	var conflict *Conflict
+	if err == nil || errors.As(err, &conflict) {
+		t.Errorf("Resolve() error = %v, want the metadata error", err)
+	}
+}
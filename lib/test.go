@@ -1,9 +1,9 @@
 //go:build ignore
-//go:build ignore
 
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http/httptest"
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/DerekCorniello/pip-req-valid/index"
 	"github.com/DerekCorniello/pip-req-valid/input"
 	"github.com/DerekCorniello/pip-req-valid/output"
 )
@@ -20,14 +21,15 @@ var testCases map[string]string = map[string]string{
         requests, numpy, flask
 No packages had errors.
 No processing errors.`,
-	"tests/test2.txt": `No verified packages.
-Found 3 error packages:
-        requests, numpy, flask
+	"tests/test2.txt": `Verified the following packages:
+        requests, flask
+Found 1 error packages:
+        numpy
 No processing errors.`,
 	"tests/test3.txt": `Verified the following packages:
-        requests, flask, https://github.com/username/special-package.git@v1.0.0
-Found 1 error packages:
-        private-package
+        requests, flask
+Found 2 error packages:
+        git+https://github.com/username/special-package.git@v1.0.0#egg=special-package, private-package
 No processing errors.`,
 	"tests/test4.txt": `Verified the following packages:
         requests, flask, pytest, black, mypy, numpy, pandas, cryptography
@@ -62,21 +64,30 @@ No processing errors.`,
 No packages had errors.
 No processing errors.`,
 	"tests/test12.txt": `No verified packages.
-Found 4 error packages:
-        -r base-requirements.txt, -e ., ../my-local-library/, ./dist/custom_package-1.0.0-py3-none-any.whl
-No processing errors.`,
+Found 3 error packages:
+        -e ., ../my-local-library/, ./dist/custom_package-1.0.0-py3-none-any.whl
+Encountered 1 processing errors:
+        tests/test12.txt:2:1: error RQ005 include-failed: cannot read included file 'tests/base-requirements.txt': open tests/base-requirements.txt: no such file or directory`,
 }
 
 func TestParseAndVerifyRequirements(t *testing.T) {
+	// index.LoadDir("tests/index") serves the PyPI JSON for every package the
+	// test files use, so these cases run without talking to PyPI. The
+	// git+https requirement in tests/test3.txt is a VCS reference, which
+	// is reported as unverifiable without going out to GitHub.
+	idx, err := index.LoadDir("tests/index")
+	if err != nil {
+		t.Fatalf("Failed to load test index: %v", err)
+	}
 	for fileName, expectedOutput := range testCases {
 		t.Run(fileName, func(t *testing.T) {
 			fileContent, err := os.ReadFile(fileName)
 			if err != nil {
 				log.Fatalf("Failed to read file: %v", err)
 			}
-			pkgs, errs := input.ParseFile(fileContent)
-			verPkgs, invPkgs, _ := input.VerifyPackages(pkgs)
-			actualOutput := output.GetPrettyOutput(verPkgs, invPkgs, errs)
+			reqFile, diags := input.ParseFile(fileName, fileContent, input.DirResolver("."), nil)
+			verPkgs, invPkgs, _ := input.VerifyPackages(context.Background(), reqFile.Requirements, reqFile.Options, input.VerifyConfig{Index: idx})
+			actualOutput := output.GetPrettyOutput(verPkgs, invPkgs, diags)
 			if actualOutput != expectedOutput {
 				t.Errorf("Output mismatch for %s\nExpected:\n%s\nGot:\n%s", fileName, expectedOutput, actualOutput)
 			}
@@ -130,7 +141,7 @@ func TestDockerCreationPass(t *testing.T) {
 				if err != nil {
 					t.Fatalf("Failed to read requirements file: %v", err)
 				}
-				_, err = RunDockerInstall(requirements)
+				_, _, err = RunDockerInstall(requirements, nil)
 				if err != nil {
 					t.Fatalf("Docker install failed: %v", err)
 				}
@@ -150,7 +161,7 @@ func TestDockerCreationFail(t *testing.T) {
 				if err != nil {
 					t.Fatalf("Failed to read requirements file: %v", err)
 				}
-				_, err = RunDockerInstall(requirements)
+				_, _, err = RunDockerInstall(requirements, nil)
 				if err == nil {
 					t.Fatalf("Docker install failed (this install should have failed): %v", err)
 				}
@@ -0,0 +1,191 @@
+package typosquat
+
+import (
+	_ "embed"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+)
+
+//go:embed popular.txt
+var bundled string
+
+// legitimate lookalikes, checked on every list so an updated popular
+// list doesn't bring the false alarms back
+//
+//go:embed allowed.txt
+var allowedNames string
+
+var allowed = names(strings.Split(allowedNames, "\n"))
+
+// List is a set of popular package names that typos get checked
+// against, in order of popularity.
+type List struct {
+	names []string
+	known map[string]bool
+	// names with the separators taken out, and names with lookalike
+	// characters folded together, each to the most popular name
+	stripped  map[string]string
+	skeletons map[string]string
+}
+
+// Reason says which heuristic matched.
+type Reason string
+
+const (
+	// `python_dateutil` vs `pythondateutil`
+	Separator Reason = "separator"
+	// `requ3sts`, `rnatplotlib`
+	Homoglyph Reason = "homoglyph"
+	// `reqeusts`, `request`
+	EditDistance Reason = "edit-distance"
+)
+
+// Match is the popular package a name looks like a typo of.
+type Match struct {
+	Name     string `json:"name"`
+	Reason   Reason `json:"reason"`
+	Distance int    `json:"distance,omitempty"`
+}
+
+// New builds a List, names are normalized and blank ones or `#`
+// comments are skipped.
+func New(lines []string) *List {
+	l := &List{known: map[string]bool{}, stripped: map[string]string{}, skeletons: map[string]string{}}
+	for _, name := range names(lines) {
+		if l.known[name] {
+			continue
+		}
+		l.known[name] = true
+		l.names = append(l.names, name)
+		if _, ok := l.stripped[strip(name)]; !ok {
+			l.stripped[strip(name)] = name
+		}
+		if _, ok := l.skeletons[skeleton(name)]; !ok {
+			l.skeletons[skeleton(name)] = name
+		}
+	}
+	return l
+}
+
+// names reads a list file, normalized, without blank lines or `#`
+// comments.
+func names(lines []string) []string {
+	result := []string{}
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			result = append(result, pep508.NormalizeName(line))
+		}
+	}
+	return result
+}
+
+// Bundled is the list that ships with the binary.
+func Bundled() *List {
+	return New(strings.Split(bundled, "\n"))
+}
+
+// Load reads a list from a file with one name per line, most popular
+// first, so it can be updated without a rebuild.
+func Load(path string) (*List, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(strings.Split(string(content), "\n")), nil
+}
+
+// Len is the number of names on the list.
+func (l *List) Len() int {
+	return len(l.names)
+}
+
+// Check looks for a popular package the name could be a typo of. Names
+// on the list, and the known legitimate lookalikes, are never reported.
+func (l *List) Check(name string) (Match, bool) {
+	name = pep508.NormalizeName(name)
+	if l.known[name] || slices.Contains(allowed, name) {
+		return Match{}, false
+	}
+	if popular, ok := l.stripped[strip(name)]; ok {
+		return Match{Name: popular, Reason: Separator}, true
+	}
+	if popular, ok := l.skeletons[skeleton(name)]; ok {
+		return Match{Name: popular, Reason: Homoglyph}, true
+	}
+
+	best := Match{}
+	for _, popular := range l.names {
+		limit := maxDistance(popular)
+		if limit == 0 || abs(len(popular)-len(name)) > limit {
+			continue
+		}
+		// the list is in popularity order, so ties go to the more
+		// popular name
+		if d := distance(name, popular); d <= limit && (best.Name == "" || d < best.Distance) {
+			best = Match{Name: popular, Reason: EditDistance, Distance: d}
+		}
+	}
+	return best, best.Name != ""
+}
+
+// short names are a letter apart from plenty of real packages, so they
+// need to be an exact lookalike
+func maxDistance(name string) int {
+	switch {
+	case len(name) < 5:
+		return 0
+	case len(name) < 10:
+		return 1
+	}
+	return 2
+}
+
+func strip(name string) string {
+	return strings.ReplaceAll(name, "-", "")
+}
+
+// lookalikes, the pairs first so `rn` becomes `m` before anything else
+var homoglyphs = strings.NewReplacer(
+	"rn", "m", "vv", "w", "cl", "d",
+	"0", "o", "1", "l", "i", "l", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b", "9", "g",
+)
+
+func skeleton(name string) string {
+	return homoglyphs.Replace(strip(name))
+}
+
+// distance is the optimal string alignment distance, Levenshtein plus
+// swapping two neighbouring letters as a single edit.
+func distance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
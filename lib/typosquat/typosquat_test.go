@@ -0,0 +1,80 @@
+package typosquat
+
+import "testing"
+
+func TestCheck(t *testing.T) {
+	list := New([]string{"requests", "numpy", "python-dateutil", "matplotlib", "attrs", "scipy", "psycopg2", "six"})
+	tests := []struct {
+		name  string
+		match Match
+		found bool
+	}{
+		// on the list, under any spelling
+		{name: "requests"},
+		{name: "Python_DateUtil"},
+		{name: "pythondateutil", match: Match{Name: "python-dateutil", Reason: Separator}, found: true},
+		{name: "python.date-util", match: Match{Name: "python-dateutil", Reason: Separator}, found: true},
+		{name: "requ3sts", match: Match{Name: "requests", Reason: Homoglyph}, found: true},
+		{name: "rnatplotlib", match: Match{Name: "matplotlib", Reason: Homoglyph}, found: true},
+		{name: "request", match: Match{Name: "requests", Reason: EditDistance, Distance: 1}, found: true},
+		{name: "reqeusts", match: Match{Name: "requests", Reason: EditDistance, Distance: 1}, found: true},
+		{name: "matplotlob", match: Match{Name: "matplotlib", Reason: EditDistance, Distance: 1}, found: true},
+		{name: "matpltolob", match: Match{Name: "matplotlib", Reason: EditDistance, Distance: 2}, found: true},
+		// too far off
+		{name: "reqstuff"},
+		{name: "matplotting"},
+		// short names need an exact lookalike
+		{name: "sux"},
+		// established lookalikes
+		{name: "attr"},
+		{name: "scapy"},
+		{name: "psycopg"},
+	}
+	for _, test := range tests {
+		match, found := list.Check(test.name)
+		if found != test.found || match != test.match {
+			t.Errorf("Check(%q) = %+v, %v, want %+v, %v", test.name, match, found, test.match, test.found)
+		}
+	}
+}
+
+func TestCheckPrefersPopular(t *testing.T) {
+	// both are one letter off, the more popular one wins
+	list := New([]string{"boto3", "botox"})
+	if match, _ := list.Check("botoz"); match.Name != "boto3" {
+		t.Errorf("Check(botoz) = %+v, want boto3", match)
+	}
+}
+
+func TestNew(t *testing.T) {
+	list := New([]string{"# comment", "", "  Requests  ", "requests", "NumPy"})
+	if list.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", list.Len())
+	}
+	if Bundled().Len() == 0 {
+		t.Error("the bundled list is empty")
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"abc", "", 3},
+		{"request", "requests", 1},
+		{"requests", "reqeusts", 1},
+		{"kitten", "sitting", 3},
+		{"ca", "abc", 3},
+	}
+	for _, test := range tests {
+		if got := distance(test.a, test.b); got != test.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+		if got := distance(test.b, test.a); got != test.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", test.b, test.a, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+func (s Severity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Code identifies a kind of finding. The ids are stable, once one is
+// handed out it never changes meaning, so tools can filter on them.
+type Code struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func (c Code) String() string {
+	return c.ID + " " + c.Name
+}
+
+var (
+	CodeUnknownPackage           = Code{"RQ001", "unknown-package"}
+	CodeUnsatisfiableSpecifier   = Code{"RQ002", "unsatisfiable-specifier"}
+	CodeInvalidRequirement       = Code{"RQ003", "invalid-requirement"}
+	CodeInvalidSpecifier         = Code{"RQ004", "invalid-specifier"}
+	CodeIncludeFailed            = Code{"RQ005", "include-failed"}
+	CodeIncludeCycle             = Code{"RQ006", "include-cycle"}
+	CodeInvalidOption            = Code{"RQ007", "invalid-option"}
+	CodeUnverifiable             = Code{"RQ008", "unverifiable"}
+	CodeIndexError               = Code{"RQ009", "index-error"}
+	CodeInstallFailed            = Code{"RQ010", "install-failed"}
+	CodeNonCanonicalName         = Code{"RQ011", "non-canonical-name"}
+	CodeInvalidMarker            = Code{"RQ012", "invalid-marker"}
+	CodeNotApplicable            = Code{"RQ013", "not-applicable"}
+	CodeResolutionImpossible     = Code{"RQ014", "resolution-impossible"}
+	CodeHashMismatch             = Code{"RQ015", "hash-mismatch"}
+	CodeMissingHash              = Code{"RQ016", "missing-hash"}
+	CodeUnknownHashAlgorithm     = Code{"RQ017", "unknown-hash-algorithm"}
+	CodeUnpinnedRequirement      = Code{"RQ018", "unpinned-requirement"}
+	CodeYankedRelease            = Code{"RQ019", "yanked-release"}
+	CodePythonIncompatible       = Code{"RQ020", "python-incompatible"}
+	CodeBuildFromSource          = Code{"RQ021", "build-from-source"}
+	CodeNoCompatibleDistribution = Code{"RQ022", "no-compatible-distribution"}
+	CodeKnownVulnerability       = Code{"RQ023", "known-vulnerability"}
+	CodeLicenseViolation         = Code{"RQ024", "license-violation"}
+	CodeUnknownLicense           = Code{"RQ025", "unknown-license"}
+	CodePossibleTyposquat        = Code{"RQ026", "possible-typosquat"}
+	CodeDependencyConfusion      = Code{"RQ027", "dependency-confusion"}
+	CodeProjectHealth            = Code{"RQ028", "project-health"}
+	CodeLockSkipped              = Code{"RQ029", "lock-skipped"}
+	CodeDuplicateRequirement     = Code{"RQ030", "duplicate-requirement"}
+)
+
+// Position is a span on one line of a file. Lines and columns start at
+// 1, and zero means unknown.
+type Position struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndColumn int    `json:"endColumn"`
+}
+
+func (pos Position) String() string {
+	switch {
+	case pos.File == "":
+		return ""
+	case pos.Line == 0:
+		return pos.File
+	case pos.Column == 0:
+		return fmt.Sprintf("%s:%d", pos.File, pos.Line)
+	}
+	return fmt.Sprintf("%s:%d:%d", pos.File, pos.Line, pos.Column)
+}
+
+// FileOrder is the order files were parsed in, the main file first and
+// each include after the file that pulled it in.
+type FileOrder []string
+
+// Compare orders two positions by file, then line, then column. Files
+// missing from the order go after the rest, by name.
+func (order FileOrder) Compare(a, b Position) int {
+	if a.File != b.File {
+		i, j := slices.Index(order, a.File), slices.Index(order, b.File)
+		switch {
+		case i >= 0 && j >= 0:
+			return cmp.Compare(i, j)
+		case i >= 0:
+			return -1
+		case j >= 0:
+			return 1
+		}
+		return strings.Compare(a.File, b.File)
+	}
+	return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+}
+
+// SortByPosition puts items in the order they appear in the files,
+// items at the same position keep their order.
+func SortByPosition[T any](items []T, order FileOrder, position func(T) Position) {
+	slices.SortStableFunc(items, func(a, b T) int {
+		return order.Compare(position(a), position(b))
+	})
+}
+
+// Diagnostic is a single finding about a requirements file.
+type Diagnostic struct {
+	Position
+	Severity Severity `json:"severity"`
+	Code     Code     `json:"code"`
+	Message  string   `json:"message"`
+	// text that could replace the span to fix the problem, if we know
+	Fix string `json:"fix,omitempty"`
+}
+
+func NewDiagnostic(pos Position, severity Severity, code Code, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{
+		Position: pos,
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// String is the usual compiler style `file:line:col: severity CODE name: message`.
+func (d Diagnostic) String() string {
+	s := fmt.Sprintf("%s %s: %s", d.Severity, d.Code, d.Message)
+	if pos := d.Position.String(); pos != "" {
+		s = pos + ": " + s
+	}
+	if d.Fix != "" {
+		s += fmt.Sprintf(" (suggested fix: '%s')", d.Fix)
+	}
+	return s
+}
+
+// Errors keeps only the error severity diagnostics.
+func Errors(diags []Diagnostic) []Diagnostic {
+	errs := []Diagnostic{}
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			errs = append(errs, d)
+		}
+	}
+	return errs
+}
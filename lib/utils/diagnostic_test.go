@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestSortByPosition(t *testing.T) {
+	order := FileOrder{"requirements.txt", "base.txt"}
+	positions := []Position{
+		{File: "zzz.txt", Line: 1},
+		{File: "base.txt", Line: 2},
+		{File: "aaa.txt", Line: 9},
+		{File: "requirements.txt", Line: 3, Column: 5},
+		{File: "base.txt", Line: 1},
+		{File: "requirements.txt", Line: 3, Column: 1},
+	}
+	SortByPosition(positions, order, func(pos Position) Position { return pos })
+	want := []Position{
+		{File: "requirements.txt", Line: 3, Column: 1},
+		{File: "requirements.txt", Line: 3, Column: 5},
+		{File: "base.txt", Line: 1},
+		{File: "base.txt", Line: 2},
+		{File: "aaa.txt", Line: 9},
+		{File: "zzz.txt", Line: 1},
+	}
+	if !slices.Equal(positions, want) {
+		t.Errorf("SortByPosition() = %v, want %v", positions, want)
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	tests := []struct {
+		diag Diagnostic
+		want string
+	}{
+		{
+			NewDiagnostic(Position{File: "requirements.txt", Line: 3, Column: 1}, SeverityError, CodeUnknownPackage, "Package '%s' not found", "reqests"),
+			"requirements.txt:3:1: error RQ001 unknown-package: Package 'reqests' not found",
+		},
+		{
+			NewDiagnostic(Position{File: "requirements.txt", Line: 2}, SeverityWarning, CodeYankedRelease, "yanked"),
+			"requirements.txt:2: warning RQ019 yanked-release: yanked",
+		},
+		{
+			NewDiagnostic(Position{File: "base.txt"}, SeverityInfo, CodeNotApplicable, "skipped"),
+			"base.txt: info RQ013 not-applicable: skipped",
+		},
+		// nothing to point at
+		{
+			NewDiagnostic(Position{}, SeverityError, CodeIndexError, "index down"),
+			"error RQ009 index-error: index down",
+		},
+		{
+			Diagnostic{
+				Position: Position{File: "requirements.txt", Line: 1, Column: 1, EndColumn: 8},
+				Severity: SeverityError, Code: CodeNonCanonicalName, Message: "misspelt", Fix: "requests",
+			},
+			"requirements.txt:1:1: error RQ011 non-canonical-name: misspelt (suggested fix: 'requests')",
+		},
+	}
+	for _, test := range tests {
+		if got := test.diag.String(); got != test.want {
+			t.Errorf("String() = %q, want %q", got, test.want)
+		}
+	}
+}
+
+func TestDiagnosticJSON(t *testing.T) {
+	diag := NewDiagnostic(Position{File: "requirements.txt", Line: 2, Column: 1, EndColumn: 6}, SeverityWarning, CodeYankedRelease, "yanked")
+	got, err := json.Marshal(diag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"file":"requirements.txt","line":2,"column":1,"endColumn":6,"severity":"warning","code":{"id":"RQ019","name":"yanked-release"},"message":"yanked"}`
+	if string(got) != want {
+		t.Errorf("json.Marshal() = %s, want %s", got, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	diags := []Diagnostic{
+		{Severity: SeverityWarning, Message: "a"},
+		{Severity: SeverityError, Message: "b"},
+		{Severity: SeverityInfo, Message: "c"},
+		{Severity: SeverityError, Message: "d"},
+	}
+	got := []string{}
+	for _, d := range Errors(diags) {
+		got = append(got, d.Message)
+	}
+	if !slices.Equal(got, []string{"b", "d"}) {
+		t.Errorf("Errors() = %v, want b and d", got)
+	}
+	// never nil, so it goes out as [] rather than null
+	if errs := Errors(nil); errs == nil || len(errs) != 0 {
+		t.Errorf("Errors(nil) = %#v, want an empty slice", errs)
+	}
+}
@@ -1,57 +1,55 @@
 package utils
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"slices"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
 )
 
-func GetAllowedPackageVersions(pkg *Package, details *[]string) ([]string, error) {
+// ErrUnverifiable is returned for direct references there's no way to
+// check from here, VCS checkouts and local files.
+var ErrUnverifiable = errors.New("cannot be verified")
+
+// GetAllowedPackageVersions lists every version of the package on the
+// index, yanked ones included. ctx bounds all of the requests made.
+func GetAllowedPackageVersions(ctx context.Context, pkg *Package, idx index.PackageIndex) ([]index.Version, error) {
 	if pkg.Name == "" {
 		return nil, nil
 	} else if slices.Contains(pkg.VersionSpecs, "local") {
 		return nil, nil
 	}
-	var url string
-	isURL := slices.Contains(pkg.VersionSpecs, "url")
-	if isURL {
-		url = pkg.Name
-	} else {
-		url = fmt.Sprintf("https://pypi.org/pypi/%s/json", pkg.Name)
-	}
-
-	// Perform HTTP GET request
-	resp, err := http.Get(url)
-	if err != nil {
-		*details = append(*details, fmt.Sprintf("An error occurred: %v\n", err))
-		return nil, err
-	}
-	defer resp.Body.Close() // Ensure the response body is closed
-
-	if !isURL {
 
-		var packageInfo map[string]interface{}
-		err = json.NewDecoder(resp.Body).Decode(&packageInfo)
+	// direct urls only need to be reachable
+	if slices.Contains(pkg.VersionSpecs, "url") {
+		location := pkg.Location()
+		scheme, _, _ := strings.Cut(location, ":")
+		// `git+https://...@v1.0` names a revision only the VCS can check
+		// out, and a file is on the machine pip runs on
+		if strings.Contains(scheme, "+") {
+			return nil, fmt.Errorf("'%s' is a VCS reference: %w", location, ErrUnverifiable)
+		} else if strings.EqualFold(scheme, "file") {
+			return nil, fmt.Errorf("'%s' is a local file: %w", location, ErrUnverifiable)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
 		if err != nil {
-			*details = append(*details, fmt.Sprintf("Error parsing JSON response: %v\n%v\n", err, packageInfo))
 			return nil, err
 		}
-
-		// Extract the "releases" map
-		releases, ok := packageInfo["releases"].(map[string]interface{})
-		if !ok {
-			*details = append(*details, fmt.Sprintf("Error: Package %v was not found.", pkg))
-			return nil, fmt.Errorf("Package with specified version was not found.")
+		// Perform HTTP GET request
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
 		}
-
-		// Collect the versions (keys of the "releases" map)
-		var versions []string
-		for version := range releases {
-			versions = append(versions, version)
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("'%s' returned %s", location, resp.Status)
 		}
-		return versions, nil
+		return []index.Version{{Version: "latest"}}, nil
 	}
 
-	return []string{"latest"}, nil
+	return idx.Versions(ctx, pkg.CanonicalName())
 }
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DerekCorniello/pip-req-valid/index"
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+)
+
+func TestGetAllowedPackageVersionsDirectReference(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pkg-1.0.tar.gz" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		line         string
+		ok           bool
+		unverifiable bool
+	}{
+		{line: "pkg @ " + server.URL + "/pkg-1.0.tar.gz", ok: true},
+		{line: "pkg @ " + server.URL + "/missing.tar.gz"},
+		{line: "pkg @ git+https://github.com/example/pkg.git@v1.0", unverifiable: true},
+		{line: "pkg @ hg+https://hg.example.com/pkg", unverifiable: true},
+		{line: "pkg @ file:///tmp/pkg-1.0.tar.gz", unverifiable: true},
+	}
+	for _, test := range tests {
+		req, err := pep508.Parse(test.line)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", test.line, err)
+		}
+		pkg := NewPackage(req)
+		versions, err := GetAllowedPackageVersions(context.Background(), &pkg, index.Memory{})
+		switch {
+		case test.ok && (err != nil || len(versions) != 1):
+			t.Errorf("%s: got %v, %v, want it reachable", test.line, versions, err)
+		case test.unverifiable && !errors.Is(err, ErrUnverifiable):
+			t.Errorf("%s: error = %v, want ErrUnverifiable", test.line, err)
+		case !test.ok && !test.unverifiable && (err == nil || errors.Is(err, ErrUnverifiable)):
+			t.Errorf("%s: error = %v, want the status reported", test.line, err)
+		}
+	}
+}
@@ -1,8 +1,150 @@
 package utils
 
+import (
+	"strings"
+	"time"
+
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+)
+
 type Package struct {
 	Name         string
 	VersionSpecs []string
 	Extras       string
 	EnvMarker    string
+	// the parsed PEP 508 form, nil for bare urls and local refs
+	Requirement *pep508.Requirement
+	// where the package was declared, includes can spread a set of
+	// requirements over several files
+	Position
+	// where the version of an exact pin is, so a fix can replace just
+	// that, zero for anything else
+	PinPosition Position
+	// per-requirement options, `--hash` values and anything else like
+	// `--config-settings` kept as `name=value`
+	Hashes         []string
+	InstallOptions []string
+	// names of the targets whose environment the marker holds in, set
+	// by verification
+	Targets []string
+	// whether it installs on each of the interpreters asked for, set by
+	// verification
+	Pythons []PythonSupport
+	// the targets with no wheel for it, pip builds the sdist there
+	SourceBuilds []string
+	// known vulnerabilities of the version that gets installed, set by
+	// verification when there's a database to check against
+	Vulnerabilities *VulnerabilityScan
+	// the license of the version that gets installed, set by
+	// verification when licenses are checked
+	License *LicenseInfo
+	// risk signals about the project as a whole, set by verification
+	// when health is checked
+	Health *Health
+}
+
+// PythonSupport says whether a package can be installed on one
+// interpreter, and at which version.
+type PythonSupport struct {
+	Python string `json:"python"`
+	// what pip would install there, empty when nothing fits
+	Version string `json:"version,omitempty"`
+	// of Version, or of the newest allowed version when nothing fits
+	RequiresPython string `json:"requires_python,omitempty"`
+	Compatible     bool   `json:"compatible"`
+	// the newest version that supports the interpreter, when none of
+	// the ones the specifiers allow do
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// VulnerabilityScan is what a package's installed version was found to
+// be affected by.
+type VulnerabilityScan struct {
+	Version         string          `json:"version"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities"`
+	// the smallest version on the index past Version that none of the
+	// package's advisories affect, empty if there isn't one yet
+	Upgrade string `json:"upgrade,omitempty"`
+}
+
+// Vulnerability is one advisory, ID is the OSV id and Aliases the CVE
+// and GHSA ids it's also known by.
+type Vulnerability struct {
+	ID      string   `json:"id"`
+	Aliases []string `json:"aliases,omitempty"`
+	Summary string   `json:"summary,omitempty"`
+	// low, medium, high, critical or unknown, and the CVSS vector it
+	// was worked out from
+	Severity string   `json:"severity"`
+	CVSS     string   `json:"cvss,omitempty"`
+	FixedIn  []string `json:"fixed_in,omitempty"`
+	// the smallest version on the index past the installed one that
+	// this advisory doesn't affect
+	Upgrade string `json:"upgrade,omitempty"`
+}
+
+// LicenseInfo is a package's license as an SPDX expression, like `MIT`
+// or `Apache-2.0 OR BSD-3-Clause`. Expression is empty when the
+// metadata doesn't say anything usable.
+type LicenseInfo struct {
+	Version    string `json:"version,omitempty"`
+	Expression string `json:"expression,omitempty"`
+	// license_expression, license or classifiers
+	Source string `json:"source,omitempty"`
+	// the License field as published, when it couldn't be mapped
+	Raw string `json:"raw,omitempty"`
+}
+
+// Health is what the index says about how alive a project is.
+type Health struct {
+	Version       string `json:"version,omitempty"`
+	LatestVersion string `json:"latest_version,omitempty"`
+	// the newest upload of any version, nil when the index has no dates
+	LastRelease  *time.Time `json:"last_release,omitempty"`
+	Releases     int        `json:"releases"`
+	Inactive     bool       `json:"inactive,omitempty"`
+	Deprecated   bool       `json:"deprecated,omitempty"`
+	MajorsBehind int        `json:"majors_behind,omitempty"`
+	// a short description of each signal that tripped, empty for a
+	// healthy project
+	Signals []string `json:"signals"`
+}
+
+// NewPackage fills in the flat fields from a parsed requirement so
+// the rest of the code can keep working off of them.
+func NewPackage(req *pep508.Requirement) Package {
+	pkg := Package{
+		Name:        req.Name,
+		Extras:      strings.Join(req.Extras, ","),
+		Requirement: req,
+	}
+	if req.URL != "" {
+		pkg.VersionSpecs = []string{"latest", "url"}
+	} else if len(req.Specifiers) > 0 {
+		pkg.VersionSpecs = req.SpecifierStrings()
+	}
+	if req.Marker != nil {
+		pkg.EnvMarker = req.Marker.String()
+	}
+	return pkg
+}
+
+// CanonicalName is the PEP 503 normalized name, what the package should
+// be compared and looked up by. Name keeps the spelling from the file
+// for display.
+func (pkg Package) CanonicalName() string {
+	return pep508.NormalizeName(pkg.Name)
+}
+
+// Location is where to go looking for the package. This is the url for
+// direct references, the canonical name for index packages and the line
+// as written for bare urls and local refs.
+func (pkg Package) Location() string {
+	if pkg.Requirement == nil {
+		return pkg.Name
+	}
+	if pkg.Requirement.URL != "" {
+		return pkg.Requirement.URL
+	}
+	return pkg.CanonicalName()
 }
@@ -0,0 +1,274 @@
+package vuln
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/DerekCorniello/pip-req-valid/pep440"
+	"github.com/DerekCorniello/pip-req-valid/pep508"
+)
+
+// Vulnerability is an OSV advisory, only the parts we use. See
+// https://ossf.github.io/osv-schema/ for the whole format.
+type Vulnerability struct {
+	ID        string     `json:"id"`
+	Aliases   []string   `json:"aliases,omitempty"`
+	Summary   string     `json:"summary,omitempty"`
+	Details   string     `json:"details,omitempty"`
+	Withdrawn string     `json:"withdrawn,omitempty"`
+	Severity  []Severity `json:"severity,omitempty"`
+	Affected  []Affected `json:"affected"`
+	// GHSA puts its own rating here as `{"severity": "HIGH"}`
+	DatabaseSpecific map[string]any `json:"database_specific,omitempty"`
+}
+
+type Severity struct {
+	// CVSS_V3, CVSS_V4, ...
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+type Affected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Ranges   []Range  `json:"ranges,omitempty"`
+	Versions []string `json:"versions,omitempty"`
+	// same as the top level one, some databases rate per package
+	DatabaseSpecific map[string]any `json:"database_specific,omitempty"`
+}
+
+type Range struct {
+	// ECOSYSTEM for PyPI, GIT ranges are commits and get skipped
+	Type   string  `json:"type"`
+	Events []Event `json:"events"`
+}
+
+// Event is one of introduced, fixed or last_affected.
+type Event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+}
+
+// DB is a set of PyPI advisories keyed by the normalized package name.
+type DB struct {
+	packages map[string][]*Vulnerability
+}
+
+// Load reads an OSV export, either a directory of `<id>.json` files
+// (nested directories are fine) or a zip like the `PyPI/all.zip`
+// OSV publishes.
+func Load(path string) (*DB, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	db := &DB{packages: map[string][]*Vulnerability{}}
+	if !info.IsDir() {
+		archive, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		defer archive.Close()
+		for _, file := range archive.File {
+			if file.FileInfo().IsDir() || !strings.HasSuffix(file.Name, ".json") {
+				continue
+			}
+			r, err := file.Open()
+			if err != nil {
+				return nil, err
+			}
+			content, err := io.ReadAll(r)
+			r.Close()
+			if err != nil {
+				return nil, err
+			}
+			if err := db.add(file.Name, content); err != nil {
+				return nil, err
+			}
+		}
+		return db, nil
+	}
+
+	err = filepath.WalkDir(path, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			return err
+		}
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		return db.add(name, content)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (db *DB) add(name string, content []byte) error {
+	var v Vulnerability
+	if err := json.Unmarshal(content, &v); err != nil {
+		return fmt.Errorf("invalid OSV entry %s: %v", name, err)
+	}
+	if v.Withdrawn != "" {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, affected := range v.Affected {
+		pkg := pep508.NormalizeName(affected.Package.Name)
+		if affected.Package.Ecosystem != "PyPI" || seen[pkg] {
+			continue
+		}
+		seen[pkg] = true
+		db.packages[pkg] = append(db.packages[pkg], &v)
+	}
+	return nil
+}
+
+// Len is the number of advisories loaded, counted once per package.
+func (db *DB) Len() int {
+	n := 0
+	for _, vulns := range db.packages {
+		n += len(vulns)
+	}
+	return n
+}
+
+// Package gives every advisory about a package, whatever the version.
+func (db *DB) Package(name string) []*Vulnerability {
+	if db == nil {
+		return nil
+	}
+	return db.packages[pep508.NormalizeName(name)]
+}
+
+// Query gives the advisories that affect one version of a package.
+func (db *DB) Query(name string, version *pep440.Version) []*Vulnerability {
+	affecting := []*Vulnerability{}
+	for _, v := range db.Package(name) {
+		if v.Affects(name, version) {
+			affecting = append(affecting, v)
+		}
+	}
+	return affecting
+}
+
+// Affects checks the version against the listed versions and the
+// ECOSYSTEM ranges of the package.
+func (v *Vulnerability) Affects(name string, version *pep440.Version) bool {
+	for _, affected := range v.affected(name) {
+		for _, listed := range affected.Versions {
+			if l, err := pep440.Parse(listed); err == nil && l.Equal(version) {
+				return true
+			}
+		}
+		for _, r := range affected.Ranges {
+			if r.Type == "ECOSYSTEM" && r.contains(version) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FixedIn lists the versions the ranges of the package end at, oldest
+// first.
+func (v *Vulnerability) FixedIn(name string) []string {
+	fixed := []*pep440.Version{}
+	for _, affected := range v.affected(name) {
+		for _, r := range affected.Ranges {
+			for _, event := range r.Events {
+				if f, err := pep440.Parse(event.Fixed); event.Fixed != "" && err == nil {
+					fixed = append(fixed, f)
+				}
+			}
+		}
+	}
+	pep440.Sort(fixed)
+	versions := []string{}
+	for _, f := range fixed {
+		if len(versions) == 0 || versions[len(versions)-1] != f.Original() {
+			versions = append(versions, f.Original())
+		}
+	}
+	return versions
+}
+
+func (v *Vulnerability) affected(name string) []Affected {
+	name = pep508.NormalizeName(name)
+	affected := []Affected{}
+	for _, a := range v.Affected {
+		if a.Package.Ecosystem == "PyPI" && pep508.NormalizeName(a.Package.Name) == name {
+			affected = append(affected, a)
+		}
+	}
+	return affected
+}
+
+// contains walks the events in version order the way the OSV spec lays
+// out, the last one at or below the version decides.
+func (r Range) contains(version *pep440.Version) bool {
+	type event struct {
+		at   *pep440.Version
+		kind string
+	}
+	events := []event{}
+	for _, e := range r.Events {
+		var s, kind string
+		switch {
+		case e.Introduced != "":
+			s, kind = e.Introduced, "introduced"
+		case e.Fixed != "":
+			s, kind = e.Fixed, "fixed"
+		case e.LastAffected != "":
+			s, kind = e.LastAffected, "last_affected"
+		default:
+			continue
+		}
+		// "0" means from the very first release
+		if s == "0" && kind == "introduced" {
+			events = append(events, event{nil, kind})
+			continue
+		}
+		at, err := pep440.Parse(s)
+		if err != nil {
+			continue
+		}
+		events = append(events, event{at, kind})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].at == nil || events[j].at == nil {
+			return events[i].at == nil && events[j].at != nil
+		}
+		return events[i].at.LessThan(events[j].at)
+	})
+
+	affected := false
+	for _, e := range events {
+		switch e.kind {
+		case "introduced":
+			if e.at == nil || !version.LessThan(e.at) {
+				affected = true
+			}
+		case "fixed":
+			if !version.LessThan(e.at) {
+				affected = false
+			}
+		case "last_affected":
+			if version.GreaterThan(e.at) {
+				affected = false
+			}
+		}
+	}
+	return affected
+}
@@ -0,0 +1,84 @@
+package vuln
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/DerekCorniello/pip-req-valid/pep440"
+)
+
+func TestRangeContains(t *testing.T) {
+	tests := []struct {
+		name    string
+		events  []Event
+		version string
+		want    bool
+	}{
+		{"from the start", []Event{{Introduced: "0"}, {Fixed: "1.2"}}, "1.0", true},
+		{"fixed", []Event{{Introduced: "0"}, {Fixed: "1.2"}}, "1.2", false},
+		{"post release before the fix", []Event{{Introduced: "0"}, {Fixed: "1.2"}}, "1.1.post1", true},
+		{"pre release of the fix", []Event{{Introduced: "0"}, {Fixed: "1.2"}}, "1.2rc1", true},
+		{"before introduced", []Event{{Introduced: "1.0"}, {LastAffected: "1.4"}}, "0.9", false},
+		{"last affected", []Event{{Introduced: "1.0"}, {LastAffected: "1.4"}}, "1.4", true},
+		{"after last affected", []Event{{Introduced: "1.0"}, {LastAffected: "1.4"}}, "1.4.1", false},
+		{"never fixed", []Event{{Introduced: "1.0"}}, "5.0", true},
+		// events out of order, two affected spans
+		{"between spans", []Event{{Introduced: "2.0"}, {Fixed: "1.2"}, {Introduced: "1.0"}, {Fixed: "2.1"}}, "1.5", false},
+		{"second span", []Event{{Introduced: "2.0"}, {Fixed: "1.2"}, {Introduced: "1.0"}, {Fixed: "2.1"}}, "2.0.5", true},
+		{"after both", []Event{{Introduced: "2.0"}, {Fixed: "1.2"}, {Introduced: "1.0"}, {Fixed: "2.1"}}, "3.0", false},
+		{"unparseable events are skipped", []Event{{Introduced: "0"}, {Fixed: "not-a-version"}}, "9.9", true},
+	}
+	for _, test := range tests {
+		r := Range{Type: "ECOSYSTEM", Events: test.events}
+		if got := r.contains(pep440.MustParse(test.version)); got != test.want {
+			t.Errorf("%s: contains(%s) = %v, want %v", test.name, test.version, got, test.want)
+		}
+	}
+}
+
+const advisory = `{
+  "id": "GHSA-demo",
+  "affected": [
+    {
+      "package": {"ecosystem": "PyPI", "name": "Demo_Pkg"},
+      "ranges": [
+        {"type": "GIT", "events": [{"introduced": "0"}, {"fixed": "abc123"}]},
+        {"type": "ECOSYSTEM", "events": [{"introduced": "1.0"}, {"fixed": "1.5"}, {"introduced": "2.0"}, {"fixed": "2.0.3"}]}
+      ],
+      "versions": ["0.9"]
+    },
+    {"package": {"ecosystem": "npm", "name": "other"}, "ranges": []}
+  ]
+}`
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "nested"), 0o755)
+	os.WriteFile(filepath.Join(dir, "nested", "GHSA-demo.json"), []byte(advisory), 0o644)
+	os.WriteFile(filepath.Join(dir, "withdrawn.json"), []byte(`{"id": "GHSA-gone", "withdrawn": "2024-01-01T00:00:00Z",
+		"affected": [{"package": {"ecosystem": "PyPI", "name": "demo-pkg"}}]}`), 0o644)
+
+	db, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Len() != 1 || len(db.Package("other")) != 0 {
+		t.Fatalf("Load() kept %d advisories, want only the PyPI one that isn't withdrawn", db.Len())
+	}
+
+	for version, want := range map[string]bool{"0.9": true, "1.2": true, "1.5": false, "2.0.2": true, "2.0.3": false} {
+		if got := len(db.Query("demo.pkg", pep440.MustParse(version))) > 0; got != want {
+			t.Errorf("Query(%s) affected = %v, want %v", version, got, want)
+		}
+	}
+	if got := db.Package("demo-pkg")[0].FixedIn("demo-pkg"); !slices.Equal(got, []string{"1.5", "2.0.3"}) {
+		t.Errorf("FixedIn() = %v, want [1.5 2.0.3]", got)
+	}
+
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644)
+	if _, err := Load(dir); err == nil {
+		t.Errorf("Load() should fail on an invalid entry")
+	}
+}
@@ -0,0 +1,157 @@
+package vuln
+
+import (
+	"math"
+	"strings"
+)
+
+// Rating is the qualitative severity, one of low, medium, high,
+// critical, or unknown when there's nothing to go by. The score is the
+// CVSS vector it came from, if any.
+func (v *Vulnerability) Rating(name string) (rating string, score string) {
+	for _, s := range v.Severity {
+		if strings.HasPrefix(s.Type, "CVSS_V3") {
+			if base, ok := cvss3BaseScore(s.Score); ok {
+				return ratingOf(base), s.Score
+			}
+		}
+	}
+	// GHSA's own rating, per package or for the whole advisory
+	for _, affected := range v.affected(name) {
+		if rating, ok := databaseRating(affected.DatabaseSpecific); ok {
+			return rating, ""
+		}
+	}
+	if rating, ok := databaseRating(v.DatabaseSpecific); ok {
+		return rating, ""
+	}
+	if len(v.Severity) > 0 {
+		return "unknown", v.Severity[0].Score
+	}
+	return "unknown", ""
+}
+
+func databaseRating(specific map[string]any) (string, bool) {
+	severity, _ := specific["severity"].(string)
+	switch strings.ToLower(severity) {
+	case "low":
+		return "low", true
+	case "moderate", "medium":
+		return "medium", true
+	case "high":
+		return "high", true
+	case "critical":
+		return "critical", true
+	}
+	return "", false
+}
+
+func ratingOf(score float64) string {
+	switch {
+	case score >= 9:
+		return "critical"
+	case score >= 7:
+		return "high"
+	case score >= 4:
+		return "medium"
+	case score > 0:
+		return "low"
+	}
+	return "none"
+}
+
+// RatingRank orders ratings, higher is worse.
+func RatingRank(rating string) int {
+	switch rating {
+	case "critical":
+		return 4
+	case "high":
+		return 3
+	case "medium":
+		return 2
+	case "low":
+		return 1
+	}
+	return 0
+}
+
+// the base metric weights from the CVSS 3.1 specification
+var cvss3Weights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"UI": {"N": 0.85, "R": 0.62},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// cvss3BaseScore works out the base score of a vector like
+// `CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H`.
+func cvss3BaseScore(vector string) (float64, bool) {
+	parts := strings.Split(vector, "/")
+	if len(parts) < 9 || !strings.HasPrefix(parts[0], "CVSS:3") {
+		return 0, false
+	}
+	metrics := map[string]string{}
+	for _, part := range parts[1:] {
+		key, value, ok := strings.Cut(part, ":")
+		if !ok {
+			return 0, false
+		}
+		metrics[key] = value
+	}
+
+	scopeChanged := metrics["S"] == "C"
+	if !scopeChanged && metrics["S"] != "U" {
+		return 0, false
+	}
+	w := map[string]float64{}
+	for metric, weights := range cvss3Weights {
+		weight, ok := weights[metrics[metric]]
+		if !ok {
+			return 0, false
+		}
+		w[metric] = weight
+	}
+	// privileges weigh more once the scope changes
+	switch metrics["PR"] {
+	case "N":
+		w["PR"] = 0.85
+	case "L":
+		w["PR"] = 0.62
+		if scopeChanged {
+			w["PR"] = 0.68
+		}
+	case "H":
+		w["PR"] = 0.27
+		if scopeChanged {
+			w["PR"] = 0.5
+		}
+	default:
+		return 0, false
+	}
+
+	iss := 1 - (1-w["C"])*(1-w["I"])*(1-w["A"])
+	impact := 6.42 * iss
+	if scopeChanged {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	exploitability := 8.22 * w["AV"] * w["AC"] * w["PR"] * w["UI"]
+	if impact <= 0 {
+		return 0, true
+	}
+	if scopeChanged {
+		return roundUp(math.Min(1.08*(impact+exploitability), 10)), true
+	}
+	return roundUp(math.Min(impact+exploitability, 10)), true
+}
+
+// roundUp is the spec's Roundup, to one decimal and always up, done in
+// integers to dodge floating point error.
+func roundUp(x float64) float64 {
+	i := int(math.Round(x * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return float64(i/10000+1) / 10
+}
@@ -0,0 +1,81 @@
+package vuln
+
+import "testing"
+
+func TestCVSS3BaseScore(t *testing.T) {
+	// scores from the FIRST CVSS 3.1 calculator
+	tests := []struct {
+		vector string
+		want   float64
+	}{
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", 9.8},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", 10.0},
+		{"CVSS:3.1/AV:N/AC:L/PR:H/UI:N/S:C/C:H/I:H/A:H", 9.1},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N", 6.1},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:H", 7.5},
+		{"CVSS:3.0/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:H/A:H", 7.8},
+		{"CVSS:3.1/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:N/A:N", 5.9},
+		{"CVSS:3.1/AV:N/AC:L/PR:L/UI:N/S:U/C:L/I:N/A:N", 4.3},
+		{"CVSS:3.1/AV:P/AC:H/PR:H/UI:R/S:U/C:L/I:N/A:N", 1.6},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", 0},
+	}
+	for _, test := range tests {
+		got, ok := cvss3BaseScore(test.vector)
+		if !ok || got != test.want {
+			t.Errorf("cvss3BaseScore(%s) = %v, %v, want %v", test.vector, got, ok, test.want)
+		}
+	}
+
+	for _, bad := range []string{
+		"",
+		"CVSS:2.0/AV:N/AC:L/Au:N/C:P/I:P/A:P",
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:X/C:H/I:H/A:H",
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H",
+		"CVSS:3.1/AV:Q/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A",
+	} {
+		if _, ok := cvss3BaseScore(bad); ok {
+			t.Errorf("cvss3BaseScore(%q) should fail", bad)
+		}
+	}
+}
+
+func TestRoundUp(t *testing.T) {
+	tests := []struct {
+		in, want float64
+	}{
+		{4.0, 4.0},
+		{4.02, 4.1},
+		{4.00001, 4.1},
+		// below the five decimals the spec rounds to first
+		{4.000001, 4.0},
+		// floating point noise isn't a reason to round up
+		{0.1 + 0.2, 0.3},
+		{9.999, 10.0},
+	}
+	for _, test := range tests {
+		if got := roundUp(test.in); got != test.want {
+			t.Errorf("roundUp(%v) = %v, want %v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestRating(t *testing.T) {
+	tests := []struct {
+		vuln   Vulnerability
+		rating string
+	}{
+		{Vulnerability{Severity: []Severity{{Type: "CVSS_V3", Score: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}}}, "critical"},
+		{Vulnerability{DatabaseSpecific: map[string]any{"severity": "MODERATE"}}, "medium"},
+		{Vulnerability{Severity: []Severity{{Type: "CVSS_V4", Score: "CVSS:4.0/AV:N"}}}, "unknown"},
+		{Vulnerability{}, "unknown"},
+	}
+	for _, test := range tests {
+		if got, _ := test.vuln.Rating("demo"); got != test.rating {
+			t.Errorf("Rating() of %+v = %s, want %s", test.vuln, got, test.rating)
+		}
+	}
+	if RatingRank("critical") <= RatingRank("high") || RatingRank("low") <= RatingRank("unknown") {
+		t.Errorf("RatingRank() doesn't order the ratings")
+	}
+}